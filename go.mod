@@ -7,5 +7,6 @@ require (
 	github.com/mdlayher/arp v0.0.0-20191213142603-f72070a231fc
 	github.com/mostlygeek/arp v0.0.0-20170424181311-541a2129847a
 	github.com/sparrc/go-ping v0.0.0-20190613174326-4e5b6552494c
+	golang.org/x/net v0.0.0-20190313220215-9f648a60d977
 	golang.org/x/sync v0.0.0-20200625203802-6e8e738ad208
 )
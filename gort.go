@@ -21,20 +21,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/ElCap1tan/gort/internal/colorFmt"
 	"github.com/ElCap1tan/gort/internal/csvParser"
+	"github.com/ElCap1tan/gort/internal/helper"
 	"github.com/ElCap1tan/gort/internal/symbols"
 	"github.com/ElCap1tan/gort/netUtil"
+	"github.com/ElCap1tan/gort/netUtil/macLookup"
 	"github.com/ElCap1tan/gort/netUtil/pScan"
 	"github.com/fatih/color"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -43,12 +50,12 @@ var dataFolder, resultFolder = "data", "scans"
 func main() {
 	var usage = "" +
 		"Usage:\n" +
-		"\tgort [-p ports] [-mc count] [-closed] [-online] [-file] [-elevated] hosts\n" +
-		"\tMandatory argument:\n" +
+		"\tgort [-p ports] [-mc count] [-closed] [-online] [-file] [-oJ file] [-oX file] [-oG file] [-oC file] [-oH file] [-elevated] [-sS] [-sU] [-sV] [-tls] [-certwarn days] [-T template] [-rate n] [-maxhosts n] [-adaptive] [-mintimeout duration] [-maxtimeout duration] [-retries n] [-randomize-hosts] [-exclude hosts] [-exclude-file file] [-iL file] [-PE] [-PP] [-PS[=ports]] [-PA[=ports]] [-PR] [-Pn] [-skipdown] [-sn] [-vendorapi] hosts\n" +
+		"\tMandatory argument (unless -iL is given):\n" +
 		"\thosts are comma separated values that can either be\n" +
-		"\t\tA single host : 192.88.99.1 or example.com\n" +
-		"\t\tA range of hosts : 192.88.99.1-50 or 192.88.99-100.1-50\n" +
-		"\t\tA CIDR formatted host range : 192.88.99.1/24\n" +
+		"\t\tA single host : 192.88.99.1, 2001:db8::1 or example.com\n" +
+		"\t\tA range of hosts : 192.88.99.1-50, 192.88.99-100.1-50 or 2001:db8::1-ff\n" +
+		"\t\tA CIDR formatted host range : 192.88.99.1/24 or 2001:db8::/120\n" +
 		"\tOptional arguments\n" +
 		"\t\t-p\n" +
 		"\t\t\tports are comma separated values that either can be\n" +
@@ -62,10 +69,86 @@ func main() {
 		"\t\t\tIf this flag is passed only hosts confirmed as online are shown in the console output.\n" +
 		"\t\t-file\n" +
 		"\t\t\tIf this flag is passed the scan result will be saved to a file.\n" +
+		"\t\t-oJ [file]\n" +
+		"\t\t\tSaves the scan result as versioned JSON document under the given file path.\n" +
+		"\t\t-oX [file]\n" +
+		"\t\t\tSaves the scan result as nmap compatible XML document under the given file path.\n" +
+		"\t\t-oG [file]\n" +
+		"\t\t\tSaves the scan result in a grepable format with one line per host under the given file path.\n" +
+		"\t\t-oC [file]\n" +
+		"\t\t\tSaves the scan result as CSV with one row per host and port under the given file path.\n" +
+		"\t\t-oH [file]\n" +
+		"\t\t\tSaves the scan result as self-contained HTML report under the given file path.\n" +
 		"\t\t-elevated\n" +
 		"\t\t\tOnly important for Linux. If this flag is passed the ICMP echo requests will be send via raw sockets.\n" +
 		"\t\t\tYou might want to try in unprivileged mode first.\n" +
 		"\t\t\tImportant: Must be run as a super-user when this flag is used or else ping tests won't work!\n" +
+		"\t\t-sS\n" +
+		"\t\t\tOnly supported on Linux in combination with -elevated. Performs a SYN (half-open) scan via raw sockets\n" +
+		"\t\t\tinstead of establishing full connections. IPv6 targets are still scanned with full connections.\n" +
+		"\t\t-sU\n" +
+		"\t\t\tScans UDP ports instead of TCP ports. Ports that don't respond are reported as open|filtered.\n" +
+		"\t\t\tCan be combined with -sS to scan both TCP and UDP ports.\n" +
+		"\t\t-sV\n" +
+		"\t\t\tDetects the services running on open TCP ports by reading their banners and sending protocol probes.\n" +
+		"\t\t\tThe detected product and version are shown in the scan result.\n" +
+		"\t\t-tls\n" +
+		"\t\t\tPerforms a TLS handshake with open TCP ports and shows the negotiated protocol version and cipher\n" +
+		"\t\t\tas well as the subject, SANs, issuer, validity and key of the server certificate.\n" +
+		"\t\t-certwarn [int]\n" +
+		"\t\t\tSets the number of days before the expiry of a certificate it is flagged as expiring soon. If omitted defaults to 30.\n" +
+		"\t\t-T [template]\n" +
+		"\t\t\tSets the timing template by name or number: paranoid (0), sneaky (1), polite (2), normal (3), aggressive (4)\n" +
+		"\t\t\tor insane (5). The templates set the concurrency, rate, timeout and retries of the ping and port scan.\n" +
+		"\t\t\tIf omitted defaults to normal.\n" +
+		"\t\t-rate [float]\n" +
+		"\t\t\tLimits the number of packets and connections per second over all targets. Overrides the rate of the timing template.\n" +
+		"\t\t-maxhosts [int]\n" +
+		"\t\t\tSets the maximum number of hosts that are scanned in parallel. Overrides the limit of the timing template.\n" +
+		"\t\t-adaptive\n" +
+		"\t\t\tDerives the port timeout of every host from its ping round trip times and the observed response latencies.\n" +
+		"\t\t-mintimeout [duration]\n" +
+		"\t\t\tSets the lower bound of adaptive timeouts. If omitted defaults to 100ms.\n" +
+		"\t\t-maxtimeout [duration]\n" +
+		"\t\t\tSets the upper bound of adaptive timeouts. If omitted defaults to the timeout of the timing template.\n" +
+		"\t\t-retries [int]\n" +
+		"\t\t\tSets how often ports are probed again after a timeout or a transient error. Overrides the retries of the timing template.\n" +
+		"\t\t-randomize-hosts\n" +
+		"\t\t\tScans the hosts in random order to spread the load over all subnets.\n" +
+		"\t\t-exclude [hosts]\n" +
+		"\t\t\tComma separated list of hosts that are never touched. Accepts the same formats as the hosts argument.\n" +
+		"\t\t-exclude-file [file]\n" +
+		"\t\t\tReads hosts that are never touched from a file with one or more comma separated hosts per line.\n" +
+		"\t\t\tBlank lines and everything after a # are ignored.\n" +
+		"\t\t-iL [file]\n" +
+		"\t\t\tReads the hosts to scan from a file with one or more comma separated hosts per line in addition to the hosts\n" +
+		"\t\t\targument. Use - to read from the standard input. Blank lines and everything after a # are ignored.\n" +
+		"\t\t-PE\n" +
+		"\t\t\tDiscovers hosts with ICMP echo requests. This is the default if no other discovery probe is selected.\n" +
+		"\t\t-PP\n" +
+		"\t\t\tDiscovers hosts with ICMP timestamp requests. Requires the -elevated flag and only supports IPv4 hosts.\n" +
+		"\t\t-PS[=ports]\n" +
+		"\t\t\tDiscovers hosts by sending TCP SYN packets to the given ports or if omitted to the ports 80 and 443.\n" +
+		"\t\t\tWithout the -elevated flag or for IPv6 hosts TCP connections are used instead.\n" +
+		"\t\t-PA[=ports]\n" +
+		"\t\t\tDiscovers hosts by sending TCP ACK packets to the given ports or if omitted to the ports 80 and 443.\n" +
+		"\t\t\tWithout the -elevated flag or for IPv6 hosts TCP connections are used instead.\n" +
+		"\t\t-PR\n" +
+		"\t\t\tDiscovers hosts in the local networks with ARP requests or NDP neighbor solicitations.\n" +
+		"\t\t\tThe discovery probes are sent in the order ARP, ICMP echo, ICMP timestamp, TCP SYN and TCP ACK\n" +
+		"\t\t\tuntil one of them is answered.\n" +
+		"\t\t-Pn\n" +
+		"\t\t\tSkips the host discovery and treats all hosts as possibly online.\n" +
+		"\t\t-skipdown\n" +
+		"\t\t\tSkips the port scan of hosts that didn't answer any discovery probe.\n" +
+		"\t\t-sn\n" +
+		"\t\t\tOnly performs the host discovery without scanning any ports and prints a table of the live hosts\n" +
+		"\t\t\twith their MAC address and vendor. The output files only contain the hosts, e.g. -oC writes one row\n" +
+		"\t\t\tper host with empty port and proto columns.\n" +
+		"\t\t-vendorapi\n" +
+		"\t\t\tLooks up the vendors of MAC addresses that aren't found in the offline IEEE registries via the API of\n" +
+		"\t\t\tmacvendors.co. This sends the MAC addresses of local hosts to a third party. The responses are cached\n" +
+		"\t\t\tin the data folder for 30 days, so every MAC prefix is only looked up once.\n" +
 		"Examples:\n" +
 		"\t# scan the 1000 most common open ports of example.com\n" +
 		"\t\tgort example.com\n" +
@@ -77,26 +160,122 @@ func main() {
 		"\t# and only show targets confirmed as online in the scan result (Some ports could be scanned double).\n" +
 		"\t\tgort -mc 100 -p 10334,12012 -online 192.88.99.0/24\n" +
 		"\t\tor\n" +
-		"\t\tgort -mc 100 -p 10334,12012 -online 192.88.99.0-255\n"
+		"\t\tgort -mc 100 -p 10334,12012 -online 192.88.99.0-255\n" +
+		"\t# scan the hosts listed in the file hosts.txt and the hosts written to the standard input by another tool\n" +
+		"\t\tgort -iL hosts.txt\n" +
+		"\t\tinventory-export | gort -iL -\n"
 	flag.Usage = func() {
 		fmt.Printf(usage)
 	}
-	var hostArgs string
 	mostCommonCount := flag.Int("mc", 1000, "")
 	portArgs := flag.String("p", "", "")
 	onlineOnly := flag.Bool("online", false, "")
 	showClosed := flag.Bool("closed", false, "")
 	writeFile := flag.Bool("file", false, "")
+	jsonFile := flag.String("oJ", "", "")
+	xmlFile := flag.String("oX", "", "")
+	grepFile := flag.String("oG", "", "")
+	csvFile := flag.String("oC", "", "")
+	htmlFile := flag.String("oH", "", "")
 	privileged := flag.Bool("elevated", false, "")
+	synScan := flag.Bool("sS", false, "")
+	udpScan := flag.Bool("sU", false, "")
+	versionScan := flag.Bool("sV", false, "")
+	tlsInspection := flag.Bool("tls", false, "")
+	certWarnDays := flag.Int("certwarn", 30, "")
+	timingArg := flag.String("T", "normal", "")
+	rate := flag.Float64("rate", 0, "")
+	maxHosts := flag.Int("maxhosts", 0, "")
+	adaptiveTimeout := flag.Bool("adaptive", false, "")
+	minTimeout := flag.Duration("mintimeout", pScan.DefaultMinTimeout, "")
+	maxTimeout := flag.Duration("maxtimeout", 0, "")
+	retries := flag.Int("retries", -1, "")
+	randomizeHosts := flag.Bool("randomize-hosts", false, "")
+	excludeArg := flag.String("exclude", "", "")
+	excludeFile := flag.String("exclude-file", "", "")
+	inputList := flag.String("iL", "", "")
+	icmpEchoPing := flag.Bool("PE", false, "")
+	icmpTimestampPing := flag.Bool("PP", false, "")
+	var synPing, ackPing discoveryPortsFlag
+	flag.Var(&synPing, "PS", "")
+	flag.Var(&ackPing, "PA", "")
+	arpPing := flag.Bool("PR", false, "")
+	skipDiscovery := flag.Bool("Pn", false, "")
+	skipDown := flag.Bool("skipdown", false, "")
+	pingSweep := flag.Bool("sn", false, "")
+	vendorAPI := flag.Bool("vendorapi", false, "")
 
 	flag.Parse()
 
-	if flag.NArg() == 0 {
+	if flag.NArg() == 0 && *inputList == "" {
 		flag.Usage()
 		return
 	}
 
-	hostArgs = flag.Arg(0)
+	var hostSpecs []string
+	if flag.NArg() > 0 {
+		hostSpecs = strings.Split(flag.Arg(0), ",")
+	}
+	if *inputList != "" {
+		specs, err := readHostSpecFile(*inputList)
+		if err != nil {
+			colorFmt.Fatalf("%s Error reading host list '%s': %s\n", symbols.FAILURE, *inputList, err.Error())
+			return
+		}
+		hostSpecs = append(hostSpecs, specs...)
+	}
+
+	timing, err := pScan.ParseTimingTemplate(*timingArg)
+	if err != nil {
+		colorFmt.Warnf("%s Unknown timing template '%s'. Using normal timing instead...\n", symbols.INFO, *timingArg)
+	}
+	// The rate limit is shared by the host discovery and the port scan.
+	limiter := timing.RateLimiter()
+	if *rate > 0 {
+		limiter = pScan.NewRateLimiter(*rate)
+	}
+	discoveryOpts := timing.DiscoveryOptions(limiter)
+	discoveryOpts.Privileged = *privileged
+	discoveryOpts.SkipDiscovery = *skipDiscovery
+	if *arpPing {
+		discoveryOpts.Probes = append(discoveryOpts.Probes, pScan.ARPProbe)
+	}
+	if *icmpEchoPing {
+		discoveryOpts.Probes = append(discoveryOpts.Probes, pScan.ICMPEchoProbe)
+	}
+	if *icmpTimestampPing {
+		discoveryOpts.Probes = append(discoveryOpts.Probes, pScan.ICMPTimestampProbe)
+	}
+	// Without -elevated the raw TCP probes are sent as TCP connections to the same ports.
+	if synPing.set {
+		discoveryOpts.Probes = append(discoveryOpts.Probes, pScan.TCPSynProbe)
+		discoveryOpts.SynPorts = synPing.ports
+	}
+	if ackPing.set {
+		discoveryOpts.Probes = append(discoveryOpts.Probes, pScan.TCPAckProbe)
+		discoveryOpts.AckPorts = ackPing.ports
+	}
+	scanOpts := timing.ScanOptions(limiter)
+	scanOpts.ServiceDetection = *versionScan
+	scanOpts.TLSInspection = *tlsInspection
+	scanOpts.CertExpiryWarning = time.Duration(*certWarnDays) * 24 * time.Hour
+	if *maxHosts > 0 {
+		scanOpts.MaxHosts = *maxHosts
+	}
+	scanOpts.AdaptiveTimeout = *adaptiveTimeout
+	scanOpts.MinTimeout = *minTimeout
+	scanOpts.MaxTimeout = *maxTimeout
+	if *retries >= 0 {
+		scanOpts.Retries = *retries
+	}
+	scanOpts.SkipDown = *skipDown
+	if *synScan {
+		if *privileged {
+			scanOpts.Technique = pScan.SynScan
+		} else {
+			colorFmt.Warnf("%s SYN scans require the -elevated flag. Falling back to connect scan...\n", symbols.INFO)
+		}
+	}
 
 	// Try to get execution path of the program. If successful tries to save the needed data in the same folder as
 	// the executable is located in. If not the data will be saved in the current working directory.
@@ -105,7 +284,7 @@ func main() {
 	}
 
 	// Try to update port-numbers.xml and port_open_freq.csv if necessary
-	err := ensureDir(dataFolder)
+	err = ensureDir(dataFolder)
 	if err != nil {
 		colorFmt.Fatalf("%s Error creating data dir '%s': %s", symbols.FAILURE, dataFolder, err.Error())
 		return
@@ -118,43 +297,152 @@ func main() {
 	if err != nil {
 		colorFmt.Warnf("%s Error while updating list of most common open ports. Using old list...\n", symbols.INFO)
 	}
+	err = updateVendorRegistries(30)
+	if err != nil {
+		colorFmt.Warnf("%s Error while updating the MAC vendor registries. Using old registries...\n", symbols.INFO)
+	}
+	vendorDB, err := macLookup.LoadVendorDB(dataFolder)
+	if err != nil {
+		colorFmt.Warnf("%s Error loading the MAC vendor registries: %s. Vendor lookups are only possible with -vendorapi...\n",
+			symbols.INFO, err.Error())
+	}
+	// Vendors are looked up in the offline registries first. API responses are cached on disk,
+	// so every MAC prefix is only sent once.
+	var vendorResolver macLookup.ChainResolver
+	if vendorDB != nil {
+		vendorResolver = append(vendorResolver, vendorDB)
+	}
+	var vendorCache *macLookup.VendorCache
+	if *vendorAPI {
+		vendorCache = macLookup.NewVendorCache(macLookup.HTTPResolver{}, macLookup.DefaultVendorCacheTTL,
+			path.Join(dataFolder, "vendor_cache.json"))
+		vendorResolver = append(vendorResolver, vendorCache)
+	}
+	discoveryOpts.VendorResolver = vendorResolver
 
-	if *portArgs == "" || *portArgs != "" && *mostCommonCount != 1000 {
-		mostCommon := csvParser.NewMostCommonPorts(dataFolder)
-		maxAvailable := 0
-		for _, p := range *mostCommon {
-			if p.Protocol == "tcp" {
-				maxAvailable++
-			}
+	// Ports are scanned over TCP unless only a UDP scan was requested. A ping sweep doesn't scan any ports.
+	var protocols []string
+	if !*pingSweep && (!*udpScan || *synScan) {
+		protocols = append(protocols, "tcp")
+	}
+	if !*pingSweep && *udpScan {
+		protocols = append(protocols, "udp")
+	}
+
+	var ports netUtil.Ports
+	for _, proto := range protocols {
+		ports = append(ports, netUtil.ParsePortString(getPortString(*portArgs, proto, *mostCommonCount), proto, dataFolder)...)
+	}
+
+	// The first interrupt cancels the running scan, so the partial results can still be printed and saved.
+	// Any further interrupt terminates the program immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		colorFmt.Warnf("\n%s Interrupted. Stopping scan and printing partial results...\n", symbols.INFO)
+		cancel()
+	}()
+
+	// Excluded hosts are never touched, so the scan is aborted if the exclude file can't be read.
+	excludeSpecs := strings.Split(*excludeArg, ",")
+	if *excludeFile != "" {
+		specs, err := readHostSpecFile(*excludeFile)
+		if err != nil {
+			colorFmt.Fatalf("%s Error reading exclude file '%s': %s\n", symbols.FAILURE, *excludeFile, err.Error())
+			return
 		}
+		excludeSpecs = append(excludeSpecs, specs...)
+	}
+	discoveryOpts.Exclude = pScan.NewExcludeList(ctx, excludeSpecs)
+
+	var console io.Writer = os.Stdout
+	if runtime.GOOS == "windows" {
+		console = color.Output
+	}
+	printer := pScan.NewStreamPrinter(console, *onlineOnly, *showClosed)
 
-		if *mostCommonCount > maxAvailable {
-			colorFmt.Infof("%s Can't start scan for the %d most common open ports because stats are only available for %d ports. Using that number of ports instead...\n",
-				symbols.INFO, *mostCommonCount, maxAvailable)
-			*mostCommonCount = maxAvailable
+	// Grepable and CSV results are written as soon as the scan of a single target is finished.
+	var streamWriters []*streamWriter
+	if *grepFile != "" {
+		if file := createResultFile(*grepFile, "Grepable"); file != nil {
+			defer file.Close()
+			streamWriters = append(streamWriters, &streamWriter{filePath: *grepFile, format: "Grepable",
+				write: pScan.NewGrepWriter(file).Write})
 		}
-		if *portArgs == "" {
-			colorFmt.Infof("%s No port arguments provided assuming %d most common open ports...\n", symbols.INFO, *mostCommonCount)
-		} else {
-			colorFmt.Infof("%s Adding the %d most common open ports to the provided list of port arguments...\n", symbols.INFO, *mostCommonCount)
+	}
+	if *csvFile != "" {
+		if file := createResultFile(*csvFile, "CSV"); file != nil {
+			defer file.Close()
+			csvWriter := pScan.NewCSVWriter(file)
+			// The header row is written right away, so that the file is valid even if no target is scanned.
+			streamWriters = append(streamWriters, &streamWriter{filePath: *csvFile, format: "CSV",
+				write: csvWriter.Write, err: csvWriter.Start()})
 		}
-		p := *portArgs + mostCommon.GetMostCommonString(*mostCommonCount)
-		portArgs = &p
 	}
 
-	colorFmt.Infof("%s Parsing and resolving host arguments...\n", symbols.INFO)
-	targets := pScan.ParseHostString(hostArgs, netUtil.ParsePortString(*portArgs, "tcp", dataFolder), *privileged)
-	colorFmt.Infof("%s STARTING SCAN...\n", symbols.INFO)
-	multiScanRes := targets.Scan()
-	tFinished := time.Now()
-	if runtime.GOOS == "windows" {
-		_, err = color.Output.Write([]byte(multiScanRes.CustomColorString(*onlineOnly, *showClosed) + "\n"))
-		if err != nil {
-			colorFmt.Infof("Error writing colored scan result to the console. Trying uncolored...")
-			fmt.Println(multiScanRes.String())
+	var multiScanRes pScan.MultiScanResult
+	multiScanRes.Args = os.Args
+	addResult := func(scanRes *pScan.ScanResult) {
+		multiScanRes.Resolved = append(multiScanRes.Resolved, scanRes)
+		for _, sw := range streamWriters {
+			if sw.err == nil {
+				sw.err = sw.write(scanRes)
+			}
+		}
+	}
+	hosts := pScan.NewTargetIteratorFromSpecs(hostSpecs, *randomizeHosts)
+	if *pingSweep {
+		// Only the host discovery is performed, so every host is reported with an empty port result.
+		colorFmt.Infof("%s STARTING HOST DISCOVERY OF %d HOSTS...\n", symbols.INFO, hosts.Len())
+		tStart := time.Now()
+		targets := hosts.Discover(ctx, discoveryOpts)
+		for _, t := range targets {
+			if t.IPAddr == nil {
+				multiScanRes.Unresolved = append(multiScanRes.Unresolved, t)
+				continue
+			}
+			scanRes := pScan.NewScanResult(t, tStart)
+			scanRes.EndTime = time.Now()
+			addResult(scanRes)
+		}
+		_, _ = fmt.Fprint(console, "\n"+targets.ColorHostTable())
+		for _, t := range multiScanRes.Unresolved {
+			colorFmt.Warnf("%s Couldn't resolve '%s'\n", symbols.INFO, t.InitialTarget)
 		}
 	} else {
-		fmt.Println(multiScanRes.CustomColorString(*onlineOnly, *showClosed))
+		// The targets are resolved lazily and scanned as soon as they are resolved.
+		addUnresolved := func(t *pScan.Target) {
+			multiScanRes.Unresolved = append(multiScanRes.Unresolved, t)
+		}
+		colorFmt.Infof("%s STARTING SCAN OF %d HOSTS...\n", symbols.INFO, hosts.Len())
+		_ = printer.Start()
+		for scanRes := range pScan.ScanTargetStream(ctx, hosts.Resolve(ctx, ports, discoveryOpts), scanOpts, addUnresolved) {
+			addResult(scanRes)
+			err = printer.Write(scanRes)
+			if err != nil {
+				colorFmt.Infof("Error writing colored scan result to the console. Trying uncolored...")
+				fmt.Println(scanRes.String())
+			}
+		}
+		_ = printer.Finish(multiScanRes.Unresolved)
+	}
+	tFinished := time.Now()
+	if vendorCache != nil {
+		if err = vendorCache.Save(); err != nil {
+			colorFmt.Warnf("%s Error saving the MAC vendor cache: %s\n", symbols.INFO, err.Error())
+		}
+	}
+
+	for _, sw := range streamWriters {
+		if sw.err != nil {
+			colorFmt.Fatalf("%s Error writing %s output file '%s': %s\n", symbols.FAILURE, sw.format, sw.filePath, sw.err.Error())
+		} else {
+			colorFmt.Infof("%s %s scan result saved as '%s'\n", symbols.INFO, sw.format, sw.filePath)
+		}
 	}
 
 	if *writeFile {
@@ -190,6 +478,136 @@ func main() {
 			}
 		}
 	}
+
+	if *jsonFile != "" {
+		writeResultFile(*jsonFile, "JSON", multiScanRes.WriteJSON)
+	}
+	if *xmlFile != "" {
+		writeResultFile(*xmlFile, "XML", multiScanRes.WriteNmapXML)
+	}
+	if *htmlFile != "" {
+		writeResultFile(*htmlFile, "HTML", multiScanRes.WriteHTML)
+	}
+}
+
+// streamWriter writes the results of a streamed scan to an output file as soon as they are available.
+type streamWriter struct {
+	filePath string
+	format   string
+	write    func(s *pScan.ScanResult) error
+	err      error
+}
+
+// discoveryPortsFlag is a command line flag that can either be passed without value to send discovery probes to the
+// default discovery ports or with a comma separated list of ports and port ranges like -PS=22,80-90.
+type discoveryPortsFlag struct {
+	set   bool
+	ports []uint16
+}
+
+// String implements the flag.Value interface.
+func (f *discoveryPortsFlag) String() string {
+	return ""
+}
+
+// IsBoolFlag allows the flag to be passed without value.
+func (f *discoveryPortsFlag) IsBoolFlag() bool {
+	return true
+}
+
+// Set implements the flag.Value interface.
+func (f *discoveryPortsFlag) Set(value string) error {
+	if value == "true" || value == "false" {
+		f.set = value == "true"
+		return nil
+	}
+	f.set = true
+	for _, portArg := range strings.Split(value, ",") {
+		if strings.Contains(portArg, "-") {
+			for _, port := range helper.StrRangeToArray(portArg) {
+				if !helper.ValidatePort(strconv.Itoa(port)) {
+					return fmt.Errorf("invalid port range '%s'", portArg)
+				}
+				f.ports = append(f.ports, uint16(port))
+			}
+		} else if port, err := strconv.ParseUint(portArg, 10, 16); err == nil {
+			f.ports = append(f.ports, uint16(port))
+		} else {
+			return fmt.Errorf("invalid port '%s'", portArg)
+		}
+	}
+	return nil
+}
+
+// getPortString returns the port arguments used for the scan of proto ports. If no port arguments are provided
+// or the number of most common ports was set explicitly, the mostCommonCount most common open ports of proto
+// are added to portArgs.
+func getPortString(portArgs, proto string, mostCommonCount int) string {
+	if portArgs != "" && mostCommonCount == 1000 {
+		return portArgs
+	}
+	mostCommon := csvParser.NewMostCommonPorts(dataFolder)
+	maxAvailable := 0
+	for _, p := range *mostCommon {
+		if p.Protocol == proto {
+			maxAvailable++
+		}
+	}
+
+	if mostCommonCount > maxAvailable {
+		colorFmt.Infof("%s Can't start scan for the %d most common open %s ports because stats are only available for %d ports. Using that number of ports instead...\n",
+			symbols.INFO, mostCommonCount, strings.ToUpper(proto), maxAvailable)
+		mostCommonCount = maxAvailable
+	}
+	if portArgs == "" {
+		colorFmt.Infof("%s No port arguments provided assuming %d most common open %s ports...\n",
+			symbols.INFO, mostCommonCount, strings.ToUpper(proto))
+		return mostCommon.GetMostCommonString(mostCommonCount, proto)
+	}
+	colorFmt.Infof("%s Adding the %d most common open %s ports to the provided list of port arguments...\n",
+		symbols.INFO, mostCommonCount, strings.ToUpper(proto))
+	return portArgs + "," + mostCommon.GetMostCommonString(mostCommonCount, proto)
+}
+
+// createResultFile creates the output file under filePath and returns it or nil if the file couldn't be created.
+// format is the name of the output format and only used for status messages.
+func createResultFile(filePath, format string) *os.File {
+	file, err := os.Create(filePath)
+	if err != nil {
+		colorFmt.Fatalf("%s Error creating %s output file '%s': %s\n", symbols.FAILURE, format, filePath, err.Error())
+		return nil
+	}
+	return file
+}
+
+// writeResultFile creates the file under filePath and writes the scan result to it by calling write.
+// format is the name of the output format and only used for status messages.
+func writeResultFile(filePath, format string, write func(w io.Writer) error) {
+	file := createResultFile(filePath, format)
+	if file == nil {
+		return
+	}
+	defer file.Close()
+	err := write(file)
+	if err != nil {
+		colorFmt.Fatalf("%s Error writing %s output file '%s': %s\n", symbols.FAILURE, format, filePath, err.Error())
+		return
+	}
+	colorFmt.Infof("%s %s scan result saved as '%s'\n", symbols.INFO, format, filePath)
+}
+
+// readHostSpecFile returns the host specifications contained in the file under filePath.
+// If filePath is -, the host specifications are read from the standard input.
+func readHostSpecFile(filePath string) ([]string, error) {
+	if filePath == "-" {
+		return pScan.ReadHostSpecs(os.Stdin)
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return pScan.ReadHostSpecs(file)
 }
 
 func updateKnownPorts(maxAgeDays int) error {
@@ -228,22 +646,62 @@ func updatePortFreq(maxAgeDays int) error {
 	return err
 }
 
+// vendorRegistryURLs contains the download URLs of the IEEE registries of MAC address blocks by their file name.
+var vendorRegistryURLs = map[string]string{
+	"oui.csv":   "https://standards-oui.ieee.org/oui/oui.csv",
+	"mam.csv":   "https://standards-oui.ieee.org/oui28/mam.csv",
+	"oui36.csv": "https://standards-oui.ieee.org/oui36/oui36.csv",
+}
+
+func updateVendorRegistries(maxAgeDays int) error {
+	var lastErr error
+	for _, fileName := range csvParser.OUIRegistryFiles {
+		vrPath := path.Join(dataFolder, fileName)
+		url := vendorRegistryURLs[fileName]
+		vrStats, err := os.Stat(vrPath)
+		if err != nil {
+			colorFmt.Warnf("%s MAC vendor registry under %s not found. Trying to download it from %s...\n", symbols.INFO, vrPath, url)
+			err = fetchFile(url, vrPath)
+		} else if vrStats.ModTime().Add(time.Hour * 24 * time.Duration(maxAgeDays)).Before(time.Now()) {
+			colorFmt.Infof("%s MAC vendor registry %s not updated since %d days. Trying to update now...\n", symbols.INFO, fileName, maxAgeDays)
+			err = fetchFile(url, vrPath)
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// fetchFile downloads url to filePath. The file is only replaced once the download succeeded,
+// so a failed update keeps the old file.
 func fetchFile(url, filePath string) error {
-	file, err := os.Create(filePath)
+	resp, err := http.Get(url)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	resp, err := http.Get(url)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status '%s'", resp.Status)
+	}
+	tmpPath := filePath + ".tmp"
+	file, err := os.Create(tmpPath)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 	_, err = io.Copy(file, resp.Body)
+	if cErr := file.Close(); err == nil {
+		err = cErr
+	}
 	if err == nil {
-		colorFmt.Successf("%s Success!\n", symbols.SUCCESS)
+		err = os.Rename(tmpPath, filePath)
 	}
-	return err
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	colorFmt.Successf("%s Success!\n", symbols.SUCCESS)
+	return nil
 }
 
 func ensureDir(dirName string) error {
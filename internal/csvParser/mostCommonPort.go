@@ -86,17 +86,15 @@ func NewMostCommonPorts(dataDir string) *MostCommonPorts {
 	return &commonPorts
 }
 
-func (ports *MostCommonPorts) GetMostCommonString(n int) string {
-	var i int
-	var str string
+func (ports *MostCommonPorts) GetMostCommonString(n int, proto string) string {
+	var nums []string
 	for _, mS := range *ports {
-		if i == n-1 {
+		if len(nums) == n {
 			break
 		}
-		if mS.Protocol == "tcp" {
-			str += strconv.Itoa(int(mS.Number)) + ","
-			i++
+		if mS.Protocol == proto {
+			nums = append(nums, strconv.Itoa(int(mS.Number)))
 		}
 	}
-	return str[:len(str)-2]
+	return strings.Join(nums, ",")
 }
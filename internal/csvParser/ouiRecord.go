@@ -0,0 +1,88 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package csvParser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// OUIRegistryFiles are the names of the files in the data folder that contain the IEEE registries of MAC address
+// blocks in CSV format: MA-L (OUI), MA-M and MA-S.
+var OUIRegistryFiles = []string{"oui.csv", "mam.csv", "oui36.csv"}
+
+// OUIRecord is a single assignment of an IEEE MAC address block registry.
+type OUIRecord struct {
+	// Registry is the type of the block: MA-L, MA-M or MA-S.
+	Registry string
+
+	// Assignment is the hexadecimal prefix of the block with 6, 7 or 9 digits.
+	Assignment string
+
+	// Organization is the name of the organization the block is assigned to.
+	Organization string
+
+	// Address is the postal address of the organization.
+	Address string
+}
+
+// NewOUIRecords reads the records of the IEEE registry fileName in dataDir.
+func NewOUIRecords(dataDir, fileName string) ([]*OUIRecord, error) {
+	csvFile, err := os.Open(path.Join(dataDir, fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer csvFile.Close()
+	return ReadOUIRecords(csvFile)
+}
+
+// ReadOUIRecords reads the records of an IEEE registry in CSV format from r. The header row is skipped.
+func ReadOUIRecords(r io.Reader) ([]*OUIRecord, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var records []*OUIRecord
+	for header := true; ; header = false {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if header || len(line) < 3 {
+			continue
+		}
+		for i := range line {
+			line[i] = strings.TrimSpace(line[i])
+		}
+		record := &OUIRecord{Registry: line[0], Assignment: line[1], Organization: line[2]}
+		if len(line) > 3 {
+			record.Address = line[3]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package csvParser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadOUIRecords(t *testing.T) {
+	const header = "Registry,Assignment,Organization Name,Organization Address\n"
+	tests := []struct {
+		name string
+		csv  string
+		want []*OUIRecord
+	}{
+		{"header only", header, nil},
+		{
+			"single record",
+			header + "MA-L,AABBCC,Example Networks Inc.,1 Example Street Springfield US 00000\n",
+			[]*OUIRecord{{Registry: "MA-L", Assignment: "AABBCC", Organization: "Example Networks Inc.",
+				Address: "1 Example Street Springfield US 00000"}},
+		},
+		{
+			"quoted fields and whitespace",
+			header + "MA-M, AABBCCD ,\"Example, Ltd.\",\"Unit 2, Example Road  Anytown  GB \"\n",
+			[]*OUIRecord{{Registry: "MA-M", Assignment: "AABBCCD", Organization: "Example, Ltd.",
+				Address: "Unit 2, Example Road  Anytown  GB"}},
+		},
+		{
+			"missing address",
+			header + "MA-S,AABBCCDDE,Private\n",
+			[]*OUIRecord{{Registry: "MA-S", Assignment: "AABBCCDDE", Organization: "Private"}},
+		},
+		{
+			"short lines are skipped",
+			header + "MA-L,AABBCC\n\nMA-L,DDEEFF,Example GmbH,\n",
+			[]*OUIRecord{{Registry: "MA-L", Assignment: "DDEEFF", Organization: "Example GmbH"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadOUIRecords(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadOUIRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOUIRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := "Registry,Assignment,Organization Name,Organization Address\nMA-L,AABBCC,Example Networks Inc.,\n"
+	if err = ioutil.WriteFile(filepath.Join(dir, "oui.csv"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	records, err := NewOUIRecords(dir, "oui.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Assignment != "AABBCC" {
+		t.Errorf("NewOUIRecords() = %v, want the AABBCC record", records)
+	}
+	if _, err = NewOUIRecords(dir, "mam.csv"); err == nil {
+		t.Error("NewOUIRecords() of a missing registry returned no error")
+	}
+}
@@ -26,27 +26,125 @@ import (
 	"strings"
 )
 
+// ValidateIPOrRange returns true if hosts is either a single IPv4 or IPv6 address or a range of addresses.
+// In a range every octet of an IPv4 address (192.88.99-100.1-50) or hextet of an IPv6 address (2001:db8::1-ff)
+// can be given as start-end range.
 func ValidateIPOrRange(hosts string) bool {
+	_, ok := IPRangeSegments(hosts)
+	return ok
+}
+
+// IPRangeSegments splits the address or range of addresses in hosts into its segments. Every segment contains
+// all values of the octet of an IPv4 address or the hextet of an IPv6 address in ascending order.
+// The returned slice therefore has either 4 or 8 elements. If hosts is no valid address or range, false is returned.
+func IPRangeSegments(hosts string) ([][]int, bool) {
+	if strings.Contains(hosts, ":") {
+		return ipv6RangeSegments(hosts)
+	}
+	return ipv4RangeSegments(hosts)
+}
+
+func ipv4RangeSegments(hosts string) ([][]int, bool) {
 	addrParts := strings.Split(hosts, ".")
 	if len(addrParts) != 4 {
-		return false
+		return nil, false
 	}
-	for _, addrPart := range addrParts {
+	segments := make([][]int, 4)
+	for i, addrPart := range addrParts {
 		if strings.Contains(addrPart, "-") && strings.Count(addrPart, "-") == 1 {
 			hRange := strings.Split(addrPart, "-")
 			if len(hRange[0]) > 3 || len(hRange[1]) > 3 {
-				return false
+				return nil, false
 			}
 			rStart, _ := strconv.Atoi(hRange[0])
-			rEnd, _ := strconv.Atoi(hRange[0])
+			rEnd, _ := strconv.Atoi(hRange[1])
 			if rStart > rEnd || !ValidateIPSegment(hRange[0]) || !ValidateIPSegment(hRange[1]) {
-				return false
+				return nil, false
 			}
+			segments[i] = StrRangeToArray(addrPart)
 		} else if !ValidateIPSegment(addrPart) {
-			return false
+			return nil, false
+		} else {
+			n, _ := strconv.Atoi(addrPart)
+			segments[i] = []int{n}
 		}
 	}
-	return true
+	return segments, true
+}
+
+func ipv6RangeSegments(hosts string) ([][]int, bool) {
+	if !strings.Contains(hosts, "-") {
+		ip := net.ParseIP(hosts)
+		if ip == nil {
+			return nil, false
+		}
+		segments := make([][]int, 8)
+		for i := range segments {
+			segments[i] = []int{int(ip[2*i])<<8 | int(ip[2*i+1])}
+		}
+		return segments, true
+	}
+
+	// Expand the :: abbreviation, so that every hextet can be parsed on its own.
+	var addrParts []string
+	if strings.Count(hosts, "::") > 1 {
+		return nil, false
+	} else if strings.Contains(hosts, "::") {
+		halves := strings.Split(hosts, "::")
+		var head, tail []string
+		if halves[0] != "" {
+			head = strings.Split(halves[0], ":")
+		}
+		if halves[1] != "" {
+			tail = strings.Split(halves[1], ":")
+		}
+		if len(head)+len(tail) > 7 {
+			return nil, false
+		}
+		addrParts = append(addrParts, head...)
+		for i := len(head) + len(tail); i < 8; i++ {
+			addrParts = append(addrParts, "0")
+		}
+		addrParts = append(addrParts, tail...)
+	} else {
+		addrParts = strings.Split(hosts, ":")
+	}
+	if len(addrParts) != 8 {
+		return nil, false
+	}
+
+	segments := make([][]int, 8)
+	for i, addrPart := range addrParts {
+		hRange := strings.Split(addrPart, "-")
+		if len(hRange) > 2 {
+			return nil, false
+		}
+		rStart, ok := parseIPv6Segment(hRange[0])
+		if !ok {
+			return nil, false
+		}
+		rEnd := rStart
+		if len(hRange) == 2 {
+			if rEnd, ok = parseIPv6Segment(hRange[1]); !ok || rStart > rEnd {
+				return nil, false
+			}
+		}
+		for n := rStart; n <= rEnd; n++ {
+			segments[i] = append(segments[i], n)
+		}
+	}
+	return segments, true
+}
+
+func parseIPv6Segment(ipSeg string) (int, bool) {
+	if len(ipSeg) == 0 || len(ipSeg) > 4 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(ipSeg, 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
 }
 
 func ValidateIPSegment(ipSeg string) bool {
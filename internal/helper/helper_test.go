@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package helper
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestIPRangeSegments(t *testing.T) {
+	tests := []struct {
+		hosts string
+		want  [][]int
+		ok    bool
+	}{
+		{"192.0.2.1", [][]int{{192}, {0}, {2}, {1}}, true},
+		{"192.0.2-3.1-4", [][]int{{192}, {0}, {2, 3}, {1, 2, 3, 4}}, true},
+		{"192.0.2.5-5", [][]int{{192}, {0}, {2}, {5}}, true},
+		// The end of a range must not be smaller than its start.
+		{"192.0.2.10-5", nil, false},
+		{"192.0.2.1-256", nil, false},
+		{"192.0.2.1-2-3", nil, false},
+		{"192.0.2", nil, false},
+		{"192.0.2.x", nil, false},
+		{"2001:db8::1", [][]int{{0x2001}, {0xdb8}, {0}, {0}, {0}, {0}, {0}, {1}}, true},
+		{"2001:db8::1-3", [][]int{{0x2001}, {0xdb8}, {0}, {0}, {0}, {0}, {0}, {1, 2, 3}}, true},
+		{"2001:db8:0:0:0:0:0:fe-ff", [][]int{{0x2001}, {0xdb8}, {0}, {0}, {0}, {0}, {0}, {0xfe, 0xff}}, true},
+		{"::1-2", [][]int{{0}, {0}, {0}, {0}, {0}, {0}, {0}, {1, 2}}, true},
+		{"fe80-fe81::1", [][]int{{0xfe80, 0xfe81}, {0}, {0}, {0}, {0}, {0}, {0}, {1}}, true},
+		{"2001:db8::ff-1", nil, false},
+		{"2001:db8::1-10000", nil, false},
+		{"2001::db8::1-2", nil, false},
+		{"2001:db8:1-2", nil, false},
+		{"2001:db8::g-h", nil, false},
+		{"2001:db8:::1", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := IPRangeSegments(tt.hosts)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("IPRangeSegments(%q) = %v, %v, want %v, %v", tt.hosts, got, ok, tt.want, tt.ok)
+		}
+		if v := ValidateIPOrRange(tt.hosts); v != tt.ok {
+			t.Errorf("ValidateIPOrRange(%q) = %v, want %v", tt.hosts, v, tt.ok)
+		}
+	}
+}
+
+func TestIncIp(t *testing.T) {
+	tests := []struct {
+		ip, want string
+	}{
+		{"192.0.2.1", "192.0.2.2"},
+		{"192.0.2.255", "192.0.3.0"},
+		{"2001:db8::ffff", "2001:db8::1:0"},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		IncIp(ip)
+		if !ip.Equal(net.ParseIP(tt.want)) {
+			t.Errorf("IncIp(%s) = %s, want %s", tt.ip, ip, tt.want)
+		}
+	}
+}
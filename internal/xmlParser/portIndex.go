@@ -0,0 +1,133 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xmlParser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// portKey identifies the records of a single port number and transport protocol.
+type portKey struct {
+	protocol string
+	number   uint16
+}
+
+// portRange is a record of a range of port numbers. record is the position of the record in the registry.
+type portRange struct {
+	start, end uint16
+	record     int
+}
+
+// portRanges contains the range records of a single transport protocol sorted by their start. maxEnd[i] is the
+// largest end of the ranges up to i, which allows to find all ranges containing a port with a binary search.
+type portRanges struct {
+	ranges []portRange
+	maxEnd []uint16
+}
+
+// buildIndex indexes the records of the PortRegistry pointer by transport protocol and port number.
+// Single port records are stored in a map and range records in an interval list per transport protocol.
+func (r *PortRegistry) buildIndex() {
+	r.numbers = make(map[portKey]int)
+	rangesByProto := make(map[string][]portRange)
+	for i, record := range r.Records {
+		if record.Protocol == "" || record.Number == "" {
+			continue
+		}
+		start, end, ok := parsePortNumber(record.Number)
+		if !ok {
+			continue
+		}
+		if start == end {
+			key := portKey{protocol: record.Protocol, number: start}
+			if _, exists := r.numbers[key]; !exists {
+				r.numbers[key] = i
+			}
+			continue
+		}
+		rangesByProto[record.Protocol] = append(rangesByProto[record.Protocol], portRange{start: start, end: end, record: i})
+	}
+	r.ranges = make(map[string]*portRanges, len(rangesByProto))
+	for proto, ranges := range rangesByProto {
+		sort.Slice(ranges, func(i, j int) bool {
+			return ranges[i].start < ranges[j].start
+		})
+		pr := &portRanges{ranges: ranges, maxEnd: make([]uint16, len(ranges))}
+		for i, rng := range ranges {
+			pr.maxEnd[i] = rng.end
+			if i > 0 && pr.maxEnd[i-1] > rng.end {
+				pr.maxEnd[i] = pr.maxEnd[i-1]
+			}
+		}
+		r.ranges[proto] = pr
+	}
+}
+
+// Lookup returns the record of the port number and transport protocol proto or nil if there is none.
+// If several records apply to the port, the first one of the registry is returned.
+func (r *PortRegistry) Lookup(proto string, number uint16) *PortRecord {
+	r.indexOnce.Do(r.buildIndex)
+	match := -1
+	if i, ok := r.numbers[portKey{protocol: proto, number: number}]; ok {
+		match = i
+	}
+	if i := r.ranges[proto].find(number); i >= 0 && (match < 0 || i < match) {
+		match = i
+	}
+	if match < 0 {
+		return nil
+	}
+	return &r.Records[match]
+}
+
+// find returns the position of the first record of the portRanges pointer, which may be nil, that contains number
+// or -1 if there is none.
+func (pr *portRanges) find(number uint16) int {
+	if pr == nil {
+		return -1
+	}
+	match := -1
+	// Only the ranges before the first range starting after number can contain it.
+	for i := sort.Search(len(pr.ranges), func(i int) bool { return pr.ranges[i].start > number }) - 1; i >= 0 && pr.maxEnd[i] >= number; i-- {
+		if rng := pr.ranges[i]; rng.end >= number && (match < 0 || rng.record < match) {
+			match = rng.record
+		}
+	}
+	return match
+}
+
+// parsePortNumber parses the number of a record, which is either a single port or a range of ports like 8000-8010.
+func parsePortNumber(number string) (uint16, uint16, bool) {
+	bounds := strings.SplitN(number, "-", 2)
+	start, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, err = strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 16); err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+	return uint16(start), uint16(end), true
+}
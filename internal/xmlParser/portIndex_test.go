@@ -0,0 +1,112 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xmlParser
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// naiveLookup returns the first record of r that matches proto and number.
+func naiveLookup(r *PortRegistry, proto string, number uint16) *PortRecord {
+	for i, record := range r.Records {
+		if record.Protocol != proto || record.Number == "" {
+			continue
+		}
+		if start, end, ok := parsePortNumber(record.Number); ok && start <= number && number <= end {
+			return &r.Records[i]
+		}
+	}
+	return nil
+}
+
+func TestPortRegistryLookup(t *testing.T) {
+	r := &PortRegistry{Records: []PortRecord{
+		{Service: "", Protocol: "", Number: ""},
+		{Service: "unassigned", Protocol: "tcp", Number: ""},
+		{Service: "invalid", Protocol: "tcp", Number: "abc"},
+		{Service: "reversed", Protocol: "tcp", Number: "20-10"},
+		{Service: "http", Protocol: "tcp", Number: "80"},
+		{Service: "low", Protocol: "tcp", Number: "1-100"},
+		{Service: "early", Protocol: "tcp", Number: "1000-2000"},
+		{Service: "late", Protocol: "tcp", Number: "1500"},
+		{Service: "inner", Protocol: "tcp", Number: "3100-3200"},
+		{Service: "outer", Protocol: "tcp", Number: "3050-3300"},
+		{Service: "http-dup", Protocol: "tcp", Number: "80"},
+		{Service: "dns", Protocol: "udp", Number: "53"},
+		{Service: "last", Protocol: "tcp", Number: "65535"},
+	}}
+	tests := []struct {
+		proto  string
+		number uint16
+		want   string
+	}{
+		{"tcp", 80, "http"},
+		{"tcp", 81, "low"},
+		{"tcp", 1, "low"},
+		{"tcp", 100, "low"},
+		{"tcp", 15, "low"},
+		{"tcp", 1500, "early"},
+		{"tcp", 2000, "early"},
+		{"tcp", 2001, ""},
+		{"tcp", 3150, "inner"},
+		{"tcp", 3060, "outer"},
+		{"tcp", 3250, "outer"},
+		{"tcp", 3301, ""},
+		{"tcp", 65535, "last"},
+		{"tcp", 53, "low"},
+		{"udp", 53, "dns"},
+		{"udp", 80, ""},
+		{"sctp", 80, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if record := r.Lookup(tt.proto, tt.number); record != nil {
+			got = record.Service
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q, %d) = %q, want %q", tt.proto, tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestPortRegistryLookupMatchesFirstRecord(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	r := &PortRegistry{}
+	for i := 0; i < 500; i++ {
+		proto := []string{"tcp", "udp"}[rnd.Intn(2)]
+		start := rnd.Intn(2000)
+		number := fmt.Sprint(start)
+		if rnd.Intn(3) == 0 {
+			number = fmt.Sprintf("%d-%d", start, start+rnd.Intn(200))
+		}
+		r.Records = append(r.Records, PortRecord{Service: fmt.Sprint(i), Protocol: proto, Number: number})
+	}
+	for _, proto := range []string{"tcp", "udp"} {
+		for port := 0; port < 2300; port++ {
+			want, got := naiveLookup(r, proto, uint16(port)), r.Lookup(proto, uint16(port))
+			if want != got {
+				t.Fatalf("Lookup(%q, %d) = %v, want %v", proto, port, got, want)
+			}
+		}
+	}
+}
@@ -22,9 +22,15 @@ package xmlParser
 
 import (
 	"encoding/xml"
+	"sync"
 )
 
 type PortRegistry struct {
 	XMLName xml.Name     `xml:"registry"`
 	Records []PortRecord `xml:"record"`
+
+	// numbers and ranges index the Records for Lookup. They are built once on first use.
+	indexOnce sync.Once
+	numbers   map[portKey]int
+	ranges    map[string]*portRanges
 }
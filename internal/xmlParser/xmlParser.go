@@ -21,14 +21,40 @@
 package xmlParser
 
 import (
+	"encoding/gob"
 	"encoding/xml"
 	"io/ioutil"
 	"os"
 	"path"
+	"time"
 )
 
+// portRegistryCacheVersion is incremented whenever the layout of portRegistryCache changes.
+const portRegistryCacheVersion = 1
+
+// portRegistryCache is the binary form of a PortRegistry that is stored in the data folder, so the XML file only has
+// to be parsed again if it changed.
+type portRegistryCache struct {
+	Version    int
+	XMLModTime time.Time
+	XMLSize    int64
+	Records    []PortRecord
+}
+
+// NewPortRegistry returns the indexed PortRegistry of the 'service-names-port-numbers.xml' file in dataFolder.
+// The parsed registry is cached in binary form next to the XML file and only parsed again if the XML file changed.
 func NewPortRegistry(dataFolder string) (*PortRegistry, error) {
-	xmlFile, err := os.Open(path.Join(dataFolder, "service-names-port-numbers.xml"))
+	xmlPath := path.Join(dataFolder, "service-names-port-numbers.xml")
+	xmlStats, err := os.Stat(xmlPath)
+	if err != nil {
+		return nil, err
+	}
+	cachePath := path.Join(dataFolder, "service-names-port-numbers.gob")
+	if portRegistry, err := loadPortRegistryCache(cachePath, xmlStats); err == nil {
+		return portRegistry, nil
+	}
+
+	xmlFile, err := os.Open(xmlPath)
 	if err != nil {
 		return nil, err
 	}
@@ -46,5 +72,57 @@ func NewPortRegistry(dataFolder string) (*PortRegistry, error) {
 		return nil, err
 	}
 
+	_ = savePortRegistryCache(cachePath, xmlStats, &portRegistry)
 	return &portRegistry, nil
 }
+
+// loadPortRegistryCache returns the PortRegistry cached under cachePath if it was built from the XML file described
+// by xmlStats.
+func loadPortRegistryCache(cachePath string, xmlStats os.FileInfo) (*PortRegistry, error) {
+	cacheFile, err := os.Open(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	defer cacheFile.Close()
+	var cache portRegistryCache
+	if err = gob.NewDecoder(cacheFile).Decode(&cache); err != nil {
+		return nil, err
+	}
+	if cache.Version != portRegistryCacheVersion || !cache.XMLModTime.Equal(xmlStats.ModTime()) ||
+		cache.XMLSize != xmlStats.Size() {
+		return nil, os.ErrNotExist
+	}
+	return &PortRegistry{Records: cache.Records}, nil
+}
+
+// savePortRegistryCache stores portRegistry, which was built from the XML file described by xmlStats, under
+// cachePath. The file is only replaced once the registry is written completely.
+func savePortRegistryCache(cachePath string, xmlStats os.FileInfo, portRegistry *PortRegistry) error {
+	records := make([]PortRecord, len(portRegistry.Records))
+	for i, record := range portRegistry.Records {
+		// The element name is the same for every record and not needed for lookups.
+		record.XMLName = xml.Name{}
+		records[i] = record
+	}
+	tmpPath := cachePath + ".tmp"
+	cacheFile, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(cacheFile).Encode(&portRegistryCache{
+		Version:    portRegistryCacheVersion,
+		XMLModTime: xmlStats.ModTime(),
+		XMLSize:    xmlStats.Size(),
+		Records:    records,
+	})
+	if cErr := cacheFile.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, cachePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
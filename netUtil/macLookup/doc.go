@@ -18,5 +18,6 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 // SOFTWARE.
 
-// Package macLookup provides a wrapper around the vendor lookup API of 'https://macvendors.co'
+// Package macLookup provides the vendor lookup of MAC-addresses based on an offline copy of the IEEE registries
+// of MAC address blocks with a wrapper around the vendor lookup API of 'https://macvendors.co' as optional fallback
 package macLookup
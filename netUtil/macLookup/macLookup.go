@@ -21,23 +21,82 @@
 package macLookup
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"sync"
 )
 
 // MACFormatError is returned if the provided MAC-address is an unsupported format.
 var MACFormatError = errors.New("invalid mac format. Supported formats hex ':' bit '-' dot '.'")
 
-const lookUpURL, format string = "http://macvendors.co/api", "JSON"
+const format = "JSON"
 
-// LookupVendor tries to look up the vendor of hardwareAddr by sending a HTTP-request to
-// the API of 'http://macvendors.co/' and if successful returns a pointer to the VendorResult.
+// lookUpURL is the base URL of the vendor lookup API.
+var lookUpURL = "https://macvendors.co/api"
+
+// noResultError is the error message of the API for MAC-addresses whose vendor isn't known.
+const noResultError = "no result"
+
+// apiError represents an error returned from the API.
+type apiError struct {
+	Result struct {
+		Error string `json:"error"`
+	} `json:"result"`
+}
+
+// defaultResolver contains the VendorResolver LookupVendor answers from.
+var defaultResolver struct {
+	sync.RWMutex
+	resolver VendorResolver
+}
+
+// SetDefaultResolver sets the VendorResolver LookupVendor answers from. If resolver is nil, LookupVendor doesn't find
+// any vendor, which is also the default.
+func SetDefaultResolver(resolver VendorResolver) {
+	defaultResolver.Lock()
+	defer defaultResolver.Unlock()
+	defaultResolver.resolver = resolver
+}
+
+// UseVendorDB sets the offline registry LookupVendor answers from. If httpFallback is true, the vendors of addresses
+// that aren't contained in db, or of all addresses if db is nil, are looked up with LookupVendorHTTP.
+// By default there is neither a registry nor a fallback, so LookupVendor doesn't find any vendor.
+func UseVendorDB(db *VendorDB, httpFallback bool) {
+	var chain ChainResolver
+	if db != nil {
+		chain = append(chain, db)
+	}
+	if httpFallback {
+		chain = append(chain, HTTPResolver{})
+	}
+	SetDefaultResolver(chain)
+}
+
+// LookupVendor tries to look up the vendor of hardwareAddr with the VendorResolver set with SetDefaultResolver or
+// UseVendorDB and if successful returns a pointer to the VendorResult. If no resolver is set or the vendor isn't
+// found VendorNotFoundError is returned.
 func LookupVendor(hardwareAddr net.HardwareAddr) (*VendorResult, error) {
+	defaultResolver.RLock()
+	resolver := defaultResolver.resolver
+	defaultResolver.RUnlock()
+	if resolver == nil {
+		return nil, VendorNotFoundError
+	}
+	v, err := resolver.ResolveVendor(hardwareAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &VendorResult{Vendor: v}, nil
+}
+
+// LookupVendorHTTP tries to look up the vendor of hardwareAddr by sending a HTTP-request to
+// the API of 'https://macvendors.co/' and if successful returns a pointer to the VendorResult.
+// VendorNotFoundError is returned if the API doesn't know the vendor and MACFormatError if it rejects hardwareAddr.
+func LookupVendorHTTP(hardwareAddr net.HardwareAddr) (*VendorResult, error) {
 	url := fmt.Sprintf("%s/%s/%s", lookUpURL, hardwareAddr.String(), format)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -55,7 +114,11 @@ func LookupVendor(hardwareAddr net.HardwareAddr) (*VendorResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	if bytes.Contains(body, []byte("error")) {
+	var apiErr apiError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Result.Error != "" {
+		if apiErr.Result.Error == noResultError {
+			return nil, VendorNotFoundError
+		}
 		return nil, MACFormatError
 	}
 	vr := &VendorResult{}
@@ -63,6 +126,9 @@ func LookupVendor(hardwareAddr net.HardwareAddr) (*VendorResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	if vr.Vendor == nil {
+		return nil, VendorNotFoundError
+	}
 	return vr, nil
 }
 
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package macLookup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLookupVendorHTTP(t *testing.T) {
+	responses := map[string]string{
+		"aa:bb:cc:00:00:01": `{"result":{"company":"Example Networks Inc.","mac_prefix":"AA:BB:CC","type":"MA-L"}}`,
+		"00:00:5e:00:00:01": `{"result":{"error":"no result"}}`,
+		"00:00:00:00:00:00": `{"result":{"error":"invalid mac address"}}`,
+	}
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mac := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")[0]
+		_, _ = w.Write([]byte(responses[mac]))
+	}))
+	defer srv.Close()
+	defer func(url string) { lookUpURL = url }(lookUpURL)
+	lookUpURL = srv.URL
+
+	tests := []struct {
+		mac     string
+		want    string
+		wantErr error
+	}{
+		{"aa:bb:cc:00:00:01", "Example Networks Inc.", nil},
+		{"00:00:5e:00:00:01", "", VendorNotFoundError},
+		{"00:00:00:00:00:00", "", MACFormatError},
+	}
+	for _, tt := range tests {
+		vr, err := LookupVendorHTTP(mustParseMAC(t, tt.mac))
+		if err != tt.wantErr {
+			t.Errorf("LookupVendorHTTP(%s) returned error %v, want %v", tt.mac, err, tt.wantErr)
+		} else if err == nil && vr.Company != tt.want {
+			t.Errorf("LookupVendorHTTP(%s) = %s, want %s", tt.mac, vr.Company, tt.want)
+		}
+	}
+
+	// Unknown vendors reported by the API are cached, so the API is asked only once.
+	atomic.StoreInt32(&requests, 0)
+	c := NewVendorCache(HTTPResolver{}, 0, "")
+	for i := 0; i < 2; i++ {
+		if _, err := c.ResolveVendor(mustParseMAC(t, "00:00:5e:00:00:01")); err != VendorNotFoundError {
+			t.Errorf("ResolveVendor() returned error %v, want VendorNotFoundError", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("API asked %d times, want 1", n)
+	}
+}
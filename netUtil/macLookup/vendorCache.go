@@ -0,0 +1,221 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package macLookup
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultVendorCacheTTL is the time the entries of a VendorCache are valid if no other time is specified.
+const DefaultVendorCacheTTL = 30 * 24 * time.Hour
+
+// VendorCache is a VendorResolver that caches the vendors found by another VendorResolver in memory and optionally
+// on disk, so the vendor of a MAC prefix is only looked up once for all hosts. The entries are keyed by the OUI
+// (the first three octets) of the addresses and expire after a TTL. Addresses that are part of a smaller MA-M or
+// MA-S block are only answered from the cache if the block is known to contain them. An OUI whose vendor wasn't found
+// is cached as unknown as well.
+type VendorCache struct {
+	resolver VendorResolver
+	ttl      time.Duration
+	filePath string
+
+	mu      sync.Mutex
+	entries map[string][]*vendorCacheEntry
+	pending map[string]chan struct{}
+}
+
+// vendorCacheEntry is a single entry of a VendorCache.
+type vendorCacheEntry struct {
+	// Prefix is the hexadecimal prefix of the block the entry applies to. It starts with the OUI of the entry.
+	Prefix string `json:"prefix"`
+
+	// Vendor is the vendor of the block or nil if it wasn't found.
+	Vendor *Vendor `json:"vendor,omitempty"`
+
+	// Expires is the time the entry expires at.
+	Expires time.Time `json:"expires"`
+}
+
+// NewVendorCache returns a pointer to a new VendorCache for resolver whose entries expire after ttl or, if ttl isn't
+// positive, after DefaultVendorCacheTTL. If filePath isn't empty, the valid entries stored in the file are loaded
+// and VendorCache.Save stores the cache to it. A missing or unreadable file results in an empty cache.
+func NewVendorCache(resolver VendorResolver, ttl time.Duration, filePath string) *VendorCache {
+	if ttl <= 0 {
+		ttl = DefaultVendorCacheTTL
+	}
+	c := &VendorCache{
+		resolver: resolver,
+		ttl:      ttl,
+		filePath: filePath,
+		entries:  make(map[string][]*vendorCacheEntry),
+		pending:  make(map[string]chan struct{}),
+	}
+	if filePath != "" {
+		c.load()
+	}
+	return c
+}
+
+// ResolveVendor implements the VendorResolver interface. Concurrent lookups of addresses with the same OUI wait for
+// the first of them, so the resolver is asked only once. Errors other than VendorNotFoundError aren't cached.
+func (c *VendorCache) ResolveVendor(hardwareAddr net.HardwareAddr) (*Vendor, error) {
+	addr := strings.ToUpper(hex.EncodeToString(hardwareAddr))
+	if len(addr) < 6 {
+		return c.resolver.ResolveVendor(hardwareAddr)
+	}
+	oui := addr[:6]
+
+	c.mu.Lock()
+	for {
+		if e := c.lookup(oui, addr); e != nil {
+			c.mu.Unlock()
+			if e.Vendor == nil {
+				return nil, VendorNotFoundError
+			}
+			return e.Vendor, nil
+		}
+		wait, ok := c.pending[oui]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		<-wait
+		c.mu.Lock()
+	}
+	done := make(chan struct{})
+	c.pending[oui] = done
+	c.mu.Unlock()
+
+	v, err := c.resolver.ResolveVendor(hardwareAddr)
+	if err == nil && v == nil {
+		err = VendorNotFoundError
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, oui)
+	close(done)
+	if err == nil {
+		c.add(oui, &vendorCacheEntry{Prefix: blockPrefix(v, addr), Vendor: v, Expires: time.Now().Add(c.ttl)})
+	} else if err == VendorNotFoundError {
+		c.add(oui, &vendorCacheEntry{Prefix: oui, Expires: time.Now().Add(c.ttl)})
+	}
+	return v, err
+}
+
+// Save stores the valid entries of the VendorCache pointer in its file. The file is only replaced once all entries
+// are written. Save does nothing if the VendorCache has no file.
+func (c *VendorCache) Save() error {
+	if c.filePath == "" {
+		return nil
+	}
+	c.mu.Lock()
+	entries := []*vendorCacheEntry{}
+	now := time.Now()
+	for _, ouiEntries := range c.entries {
+		for _, e := range ouiEntries {
+			if e.Expires.After(now) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	b, err := json.Marshal(entries)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmpPath := c.filePath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, c.filePath); err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+// load adds the valid entries stored in the file of the VendorCache pointer.
+func (c *VendorCache) load() {
+	b, err := ioutil.ReadFile(c.filePath)
+	if err != nil {
+		return
+	}
+	var entries []*vendorCacheEntry
+	if json.Unmarshal(b, &entries) != nil {
+		return
+	}
+	now := time.Now()
+	for _, e := range entries {
+		if len(e.Prefix) >= 6 && e.Expires.After(now) {
+			c.add(e.Prefix[:6], e)
+		}
+	}
+}
+
+// lookup returns the valid entry of the most specific block of oui that contains addr or nil if there is none.
+// c.mu must be held.
+func (c *VendorCache) lookup(oui, addr string) *vendorCacheEntry {
+	var match *vendorCacheEntry
+	now := time.Now()
+	for _, e := range c.entries[oui] {
+		if e.Expires.After(now) && strings.HasPrefix(addr, e.Prefix) && (match == nil || len(e.Prefix) > len(match.Prefix)) {
+			match = e
+		}
+	}
+	return match
+}
+
+// add adds e to the entries of oui and replaces an entry with the same prefix as well as expired entries.
+// c.mu must be held.
+func (c *VendorCache) add(oui string, e *vendorCacheEntry) {
+	entries := []*vendorCacheEntry{e}
+	now := time.Now()
+	for _, old := range c.entries[oui] {
+		if old.Prefix != e.Prefix && old.Expires.After(now) {
+			entries = append(entries, old)
+		}
+	}
+	c.entries[oui] = entries
+}
+
+// registrationAuthority is the organization the OUIs that are divided into MA-M and MA-S blocks are assigned to.
+const registrationAuthority = "IEEE Registration Authority"
+
+// blockPrefix returns the hexadecimal prefix of the block of v that contains addr. The OUIs of the IEEE Registration
+// Authority are divided into smaller blocks that aren't all known, so for them the prefix of the smallest possible
+// block (MA-S) is returned. If v doesn't specify a block containing addr, the OUI of addr is returned.
+func blockPrefix(v *Vendor, addr string) string {
+	prefix := strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(v.MacPrefix))
+	if len(prefix) < 6 || !strings.HasPrefix(addr, prefix) {
+		prefix = addr[:6]
+	}
+	if strings.HasPrefix(v.Company, registrationAuthority) && len(prefix) < 9 && len(addr) >= 9 {
+		prefix = addr[:9]
+	}
+	return prefix
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package macLookup
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingResolver is a VendorResolver that answers from a VendorDB or with err and counts its lookups.
+type countingResolver struct {
+	db    *VendorDB
+	err   error
+	calls int32
+}
+
+// ResolveVendor implements the VendorResolver interface.
+func (r *countingResolver) ResolveVendor(hardwareAddr net.HardwareAddr) (*Vendor, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.db.ResolveVendor(hardwareAddr)
+}
+
+// newCountingResolver returns a pointer to a countingResolver answering from a registry whose MA-M and MA-S blocks
+// are part of the OUIs of the IEEE Registration Authority, like in the IEEE registries.
+func newCountingResolver(t *testing.T) *countingResolver {
+	t.Helper()
+	db := NewVendorDB()
+	for prefix, v := range map[string]*Vendor{
+		"AABBCC":    {Company: "Example MA-L", Type: "MA-L"},
+		"A1B2C3":    {Company: registrationAuthority, Type: "MA-L"},
+		"A1B2C3D":   {Company: "Example MA-M", Type: "MA-M"},
+		"70B3D5":    {Company: registrationAuthority, Type: "MA-L"},
+		"70B3D5123": {Company: "Example MA-S", Type: "MA-S"},
+	} {
+		if err := db.Add(prefix, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &countingResolver{db: db}
+}
+
+func mustParseMAC(t *testing.T, s string) net.HardwareAddr {
+	t.Helper()
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mac
+}
+
+func TestVendorCache(t *testing.T) {
+	type lookup struct {
+		mac       string
+		want      string
+		wantCalls int32
+	}
+	tests := []struct {
+		name    string
+		lookups []lookup
+	}{
+		{"same OUI", []lookup{
+			{"aa:bb:cc:00:00:01", "Example MA-L", 1},
+			{"aa:bb:cc:12:34:56", "Example MA-L", 1},
+		}},
+		{"unknown OUI is cached", []lookup{
+			{"00:00:5e:00:00:01", "", 1},
+			{"00:00:5e:00:00:02", "", 1},
+		}},
+		{"MA-M block", []lookup{
+			{"a1:b2:c3:d1:00:01", "Example MA-M", 1},
+			{"a1:b2:c3:df:ff:ff", "Example MA-M", 1},
+			// The MA-M entry doesn't contain the address, so the next block of the OUI is looked up
+			{"a1:b2:c3:01:20:01", registrationAuthority, 2},
+			{"a1:b2:c3:01:2f:ff", registrationAuthority, 2},
+			{"a1:b2:c3:d5:00:01", "Example MA-M", 2},
+		}},
+		{"MA-S block", []lookup{
+			{"70:b3:d5:12:30:01", "Example MA-S", 1},
+			{"70:b3:d5:12:3f:ff", "Example MA-S", 1},
+			// Unknown blocks of the OUI are only cached for their own MA-S block
+			{"70:b3:d5:ab:c0:01", registrationAuthority, 2},
+			{"70:b3:d5:ab:cf:ff", registrationAuthority, 2},
+			{"70:b3:d5:ab:d0:01", registrationAuthority, 3},
+			{"70:b3:d5:12:34:56", "Example MA-S", 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCountingResolver(t)
+			c := NewVendorCache(r, 0, "")
+			for _, l := range tt.lookups {
+				v, err := c.ResolveVendor(mustParseMAC(t, l.mac))
+				if l.want == "" {
+					if err != VendorNotFoundError {
+						t.Errorf("ResolveVendor(%s) returned %v, %v, want VendorNotFoundError", l.mac, v, err)
+					}
+				} else if err != nil || v.Company != l.want {
+					t.Errorf("ResolveVendor(%s) returned %v, %v, want %s", l.mac, v, err, l.want)
+				}
+				if calls := atomic.LoadInt32(&r.calls); calls != l.wantCalls {
+					t.Errorf("ResolveVendor(%s): resolver called %d times, want %d", l.mac, calls, l.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestVendorCacheTTL(t *testing.T) {
+	r := newCountingResolver(t)
+	c := NewVendorCache(r, 100*time.Millisecond, "")
+	tests := []struct {
+		mac       string
+		wantCalls int32
+	}{
+		{"aa:bb:cc:00:00:01", 1},
+		{"00:00:5e:00:00:01", 2},
+		{"aa:bb:cc:00:00:01", 2},
+		{"00:00:5e:00:00:01", 2},
+	}
+	for _, round := range []int32{0, 2} {
+		for _, tt := range tests {
+			_, _ = c.ResolveVendor(mustParseMAC(t, tt.mac))
+			if calls := atomic.LoadInt32(&r.calls); calls != tt.wantCalls+round {
+				t.Errorf("ResolveVendor(%s): resolver called %d times, want %d", tt.mac, calls, tt.wantCalls+round)
+			}
+		}
+		// Both the found and the unknown entry expire
+		time.Sleep(120 * time.Millisecond)
+	}
+}
+
+func TestVendorCacheErrorsAreNotCached(t *testing.T) {
+	r := &countingResolver{err: errors.New("connection refused")}
+	c := NewVendorCache(r, 0, "")
+	for i := int32(1); i <= 2; i++ {
+		if _, err := c.ResolveVendor(mustParseMAC(t, "aa:bb:cc:00:00:01")); err != r.err {
+			t.Errorf("ResolveVendor() returned error %v, want %v", err, r.err)
+		}
+		if calls := atomic.LoadInt32(&r.calls); calls != i {
+			t.Errorf("resolver called %d times, want %d", calls, i)
+		}
+	}
+}
+
+func TestVendorCacheConcurrent(t *testing.T) {
+	r := newCountingResolver(t)
+	c := NewVendorCache(r, 0, "")
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.ResolveVendor(net.HardwareAddr{0xaa, 0xbb, 0xcc, 0, 0, byte(i)})
+			if err != nil || v.Company != "Example MA-L" {
+				t.Errorf("ResolveVendor() returned %v, %v, want Example MA-L", v, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if calls := atomic.LoadInt32(&r.calls); calls != 1 {
+		t.Errorf("resolver called %d times, want 1", calls)
+	}
+}
+
+func TestVendorCacheSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "vendor_cache.json")
+
+	r := newCountingResolver(t)
+	c := NewVendorCache(r, 0, filePath)
+	macs := []string{"a1:b2:c3:d1:00:01", "00:00:5e:00:00:01", "70:b3:d5:12:30:01"}
+	for _, mac := range macs {
+		_, _ = c.ResolveVendor(mustParseMAC(t, mac))
+	}
+	if err = c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"a1:b2:c3:d2:00:01", "Example MA-M"},
+		{"00:00:5e:00:00:02", ""},
+		{"70:b3:d5:12:3a:bc", "Example MA-S"},
+	}
+	loaded := &countingResolver{err: errors.New("not expected")}
+	c = NewVendorCache(loaded, 0, filePath)
+	for _, tt := range tests {
+		v, err := c.ResolveVendor(mustParseMAC(t, tt.mac))
+		if tt.want == "" {
+			if err != VendorNotFoundError {
+				t.Errorf("ResolveVendor(%s) returned %v, %v, want VendorNotFoundError", tt.mac, v, err)
+			}
+		} else if err != nil || v.Company != tt.want {
+			t.Errorf("ResolveVendor(%s) returned %v, %v, want %s", tt.mac, v, err, tt.want)
+		}
+	}
+	if calls := atomic.LoadInt32(&loaded.calls); calls != 0 {
+		t.Errorf("resolver of the loaded cache called %d times, want 0", calls)
+	}
+
+	// Expired entries aren't saved
+	expiredPath := filepath.Join(dir, "expired.json")
+	c = NewVendorCache(r, time.Nanosecond, expiredPath)
+	_, _ = c.ResolveVendor(mustParseMAC(t, macs[0]))
+	time.Sleep(time.Millisecond)
+	if err = c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	c = NewVendorCache(loaded, 0, expiredPath)
+	if _, err = c.ResolveVendor(mustParseMAC(t, macs[0])); err != loaded.err {
+		t.Errorf("ResolveVendor() of an expired entry returned error %v, want %v", err, loaded.err)
+	}
+}
+
+func TestBlockPrefix(t *testing.T) {
+	tests := []struct {
+		vendor *Vendor
+		addr   string
+		want   string
+	}{
+		{&Vendor{Company: "Example", MacPrefix: "AA:BB:CC"}, "AABBCC123456", "AABBCC"},
+		{&Vendor{Company: "Example", MacPrefix: "AA:BB:CC:D"}, "AABBCCD23456", "AABBCCD"},
+		{&Vendor{Company: "Example", MacPrefix: "aa-bb-cc-dd-e"}, "AABBCCDDE456", "AABBCCDDE"},
+		// Prefixes that don't contain the address or are too short are replaced by the OUI
+		{&Vendor{Company: "Example", MacPrefix: "11:22:33"}, "AABBCC123456", "AABBCC"},
+		{&Vendor{Company: "Example"}, "AABBCC123456", "AABBCC"},
+		{&Vendor{Company: registrationAuthority, MacPrefix: "70:B3:D5"}, "70B3D5ABCDEF", "70B3D5ABC"},
+		{&Vendor{Company: registrationAuthority, MacPrefix: "70:B3:D5:AB:C"}, "70B3D5ABCDEF", "70B3D5ABC"},
+	}
+	for _, tt := range tests {
+		if got := blockPrefix(tt.vendor, tt.addr); got != tt.want {
+			t.Errorf("blockPrefix(%s, %s) = %s, want %s", tt.vendor.MacPrefix, tt.addr, got, tt.want)
+		}
+	}
+}
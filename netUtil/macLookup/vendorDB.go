@@ -0,0 +1,150 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package macLookup
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ElCap1tan/gort/internal/csvParser"
+	"net"
+	"strings"
+)
+
+// VendorNotFoundError is returned if the vendor of a MAC-address couldn't be found.
+var VendorNotFoundError = errors.New("vendor not found")
+
+// VendorDB is an offline registry of the MAC address blocks assigned by the IEEE. The blocks are stored in a prefix
+// trie with one level per hexadecimal digit, so a lookup always finds the most specific MA-L, MA-M or MA-S
+// assignment of an address without any network access.
+type VendorDB struct {
+	root vendorNode
+	size int
+}
+
+// vendorNode is a node of the prefix trie of a VendorDB.
+type vendorNode struct {
+	children [16]*vendorNode
+	vendor   *Vendor
+}
+
+// NewVendorDB returns a pointer to a new empty VendorDB.
+func NewVendorDB() *VendorDB {
+	return &VendorDB{}
+}
+
+// LoadVendorDB returns a pointer to a VendorDB containing the IEEE registries (MA-L, MA-M and MA-S) found in
+// dataFolder. Registries that don't exist are skipped. An error is returned if none of the registries could be read.
+func LoadVendorDB(dataFolder string) (*VendorDB, error) {
+	db := NewVendorDB()
+	var lastErr error
+	loaded := false
+	for _, fileName := range csvParser.OUIRegistryFiles {
+		records, err := csvParser.NewOUIRecords(dataFolder, fileName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		loaded = true
+		for _, r := range records {
+			_ = db.Add(r.Assignment, &Vendor{Company: r.Organization, Address: r.Address, Type: r.Registry})
+		}
+	}
+	if !loaded {
+		return nil, lastErr
+	}
+	return db, nil
+}
+
+// Add adds the block identified by the hexadecimal prefix to the VendorDB pointer and assigns it to v.
+// The MAC prefix and the first and last address of v are derived from prefix. An existing assignment of the same
+// prefix is replaced.
+func (db *VendorDB) Add(prefix string, v *Vendor) error {
+	prefix = strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(prefix))
+	if prefix == "" || len(prefix) > 12 {
+		return fmt.Errorf("invalid mac prefix '%s'", prefix)
+	}
+	node := &db.root
+	for _, c := range prefix {
+		n, ok := hexValue(c)
+		if !ok {
+			return fmt.Errorf("invalid mac prefix '%s'", prefix)
+		}
+		if node.children[n] == nil {
+			node.children[n] = &vendorNode{}
+		}
+		node = node.children[n]
+	}
+	vendor := *v
+	vendor.MacPrefix = formatPrefix(prefix)
+	vendor.StartHex = prefix + strings.Repeat("0", 12-len(prefix))
+	vendor.EndHex = prefix + strings.Repeat("F", 12-len(prefix))
+	if node.vendor == nil {
+		db.size++
+	}
+	node.vendor = &vendor
+	return nil
+}
+
+// Lookup returns the Vendor of the most specific block containing hardwareAddr or nil if there is none.
+func (db *VendorDB) Lookup(hardwareAddr net.HardwareAddr) *Vendor {
+	var vendor *Vendor
+	node := &db.root
+	for _, b := range hardwareAddr {
+		for _, n := range [2]byte{b >> 4, b & 0x0f} {
+			if node = node.children[n]; node == nil {
+				return vendor
+			}
+			if node.vendor != nil {
+				vendor = node.vendor
+			}
+		}
+	}
+	return vendor
+}
+
+// Len returns the number of blocks in the VendorDB pointer.
+func (db *VendorDB) Len() int {
+	return db.size
+}
+
+// hexValue returns the value of the hexadecimal digit c.
+func hexValue(c rune) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return byte(c - '0'), true
+	case c >= 'A' && c <= 'F':
+		return byte(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// formatPrefix returns the hexadecimal prefix with the octets separated by colons, e.g. 70:B3:D5:1 for 70B3D51.
+func formatPrefix(prefix string) string {
+	var octets []string
+	for i := 0; i < len(prefix); i += 2 {
+		end := i + 2
+		if end > len(prefix) {
+			end = len(prefix)
+		}
+		octets = append(octets, prefix[i:end])
+	}
+	return strings.Join(octets, ":")
+}
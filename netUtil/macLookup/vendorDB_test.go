@@ -0,0 +1,175 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package macLookup
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestVendorDB returns a pointer to a VendorDB with nested MA-L, MA-M and MA-S blocks.
+func newTestVendorDB(t *testing.T) *VendorDB {
+	t.Helper()
+	db := NewVendorDB()
+	for prefix, v := range map[string]*Vendor{
+		"AABBCC":        {Company: "Example MA-L", Type: "MA-L"},
+		"aa:bb:cc:d":    {Company: "Example MA-M", Type: "MA-M"},
+		"AA-BB-CC-DD-E": {Company: "Example MA-S", Type: "MA-S"},
+		"112233":        {Company: "Other MA-L", Type: "MA-L"},
+	} {
+		if err := db.Add(prefix, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestVendorDBLookup(t *testing.T) {
+	db := newTestVendorDB(t)
+	tests := []struct {
+		mac                         string
+		company, prefix, start, end string
+	}{
+		{"aa:bb:cc:00:00:01", "Example MA-L", "AA:BB:CC", "AABBCC000000", "AABBCCFFFFFF"},
+		{"aa:bb:cc:c0:00:01", "Example MA-L", "AA:BB:CC", "AABBCC000000", "AABBCCFFFFFF"},
+		{"aa:bb:cc:d1:23:45", "Example MA-M", "AA:BB:CC:D", "AABBCCD00000", "AABBCCDFFFFF"},
+		{"aa:bb:cc:dd:e1:23", "Example MA-S", "AA:BB:CC:DD:E", "AABBCCDDE000", "AABBCCDDEFFF"},
+		{"aa:bb:cc:dd:f1:23", "Example MA-M", "AA:BB:CC:D", "AABBCCD00000", "AABBCCDFFFFF"},
+		{"11:22:33:44:55:66", "Other MA-L", "11:22:33", "112233000000", "112233FFFFFF"},
+		{"aa:bb:cd:00:00:01", "", "", "", ""},
+		{"00:00:00:00:00:00", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		mac, err := net.ParseMAC(tt.mac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := db.Lookup(mac)
+		if tt.company == "" {
+			if v != nil {
+				t.Errorf("Lookup(%s) = %s, want nil", tt.mac, v.Company)
+			}
+			continue
+		}
+		if v == nil {
+			t.Errorf("Lookup(%s) = nil, want %s", tt.mac, tt.company)
+			continue
+		}
+		if v.Company != tt.company || v.MacPrefix != tt.prefix || v.StartHex != tt.start || v.EndHex != tt.end {
+			t.Errorf("Lookup(%s) = %s %s %s-%s, want %s %s %s-%s", tt.mac, v.Company, v.MacPrefix, v.StartHex, v.EndHex,
+				tt.company, tt.prefix, tt.start, tt.end)
+		}
+	}
+}
+
+func TestVendorDBAdd(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		wantErr bool
+	}{
+		{"AABBCC", false},
+		{"aabbccd", false},
+		{"AA:BB:CC:DD:EE:FF", false},
+		{"", true},
+		{"AABBCCDDEEFF0", true},
+		{"AABBGG", true},
+	}
+	for _, tt := range tests {
+		err := NewVendorDB().Add(tt.prefix, &Vendor{Company: "Example"})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Add(%q) returned error %v, want error %t", tt.prefix, err, tt.wantErr)
+		}
+	}
+
+	db := newTestVendorDB(t)
+	if err := db.Add("AABBCC", &Vendor{Company: "Replaced"}); err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 4 {
+		t.Errorf("Len() = %d after replacing a block, want 4", db.Len())
+	}
+	if v := db.Lookup(net.HardwareAddr{0xaa, 0xbb, 0xcc, 0, 0, 1}); v == nil || v.Company != "Replaced" {
+		t.Errorf("Lookup() after replacing a block = %v, want Replaced", v)
+	}
+}
+
+func TestLoadVendorDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err = LoadVendorDB(dir); err == nil {
+		t.Error("LoadVendorDB() without registries returned no error")
+	}
+
+	const header = "Registry,Assignment,Organization Name,Organization Address\n"
+	for fileName, content := range map[string]string{
+		"oui.csv":   header + "MA-L,AABBCC,Example MA-L,1 Example Street\n",
+		"oui36.csv": header + "MA-S,AABBCCDDE,Example MA-S,2 Example Street\n",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, fileName), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := LoadVendorDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", db.Len())
+	}
+	v := db.Lookup(net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xe0, 1})
+	if v == nil || v.Company != "Example MA-S" || v.Type != "MA-S" || v.Address != "2 Example Street" {
+		t.Errorf("Lookup() = %+v, want the MA-S block", v)
+	}
+}
+
+func TestLookupVendor(t *testing.T) {
+	defer UseVendorDB(nil, false)
+	db := newTestVendorDB(t)
+	tests := []struct {
+		name    string
+		db      *VendorDB
+		mac     net.HardwareAddr
+		want    string
+		wantErr error
+	}{
+		{"nothing configured", nil, net.HardwareAddr{0xaa, 0xbb, 0xcc, 0, 0, 1}, "", VendorNotFoundError},
+		{"found", db, net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xd0, 0, 1}, "Example MA-M", nil},
+		{"not found", db, net.HardwareAddr{0x00, 0x00, 0x5e, 0, 0, 1}, "", VendorNotFoundError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			UseVendorDB(tt.db, false)
+			vr, err := LookupVendor(tt.mac)
+			if err != tt.wantErr {
+				t.Fatalf("LookupVendor() returned error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && vr.Vendor.Company != tt.want {
+				t.Errorf("LookupVendor() = %s, want %s", vr.Vendor.Company, tt.want)
+			}
+		})
+	}
+}
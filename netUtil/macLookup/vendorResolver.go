@@ -0,0 +1,71 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package macLookup
+
+import (
+	"net"
+)
+
+// VendorResolver looks up the vendor of MAC-addresses, e.g. in a registry, an API or an asset database.
+// Implementations must be safe for concurrent use, as the vendors of the targets of a scan are looked up concurrently.
+type VendorResolver interface {
+	// ResolveVendor returns the vendor of hardwareAddr or VendorNotFoundError if it isn't known.
+	ResolveVendor(hardwareAddr net.HardwareAddr) (*Vendor, error)
+}
+
+// HTTPResolver is a VendorResolver that looks up vendors with the API of 'https://macvendors.co' by calling
+// LookupVendorHTTP. Every lookup sends the MAC-address to the API, so it should be wrapped in a VendorCache.
+type HTTPResolver struct{}
+
+// ResolveVendor implements the VendorResolver interface.
+func (HTTPResolver) ResolveVendor(hardwareAddr net.HardwareAddr) (*Vendor, error) {
+	vr, err := LookupVendorHTTP(hardwareAddr)
+	if err != nil {
+		return nil, err
+	}
+	return vr.Vendor, nil
+}
+
+// ResolveVendor implements the VendorResolver interface for the offline registry.
+func (db *VendorDB) ResolveVendor(hardwareAddr net.HardwareAddr) (*Vendor, error) {
+	if v := db.Lookup(hardwareAddr); v != nil {
+		return v, nil
+	}
+	return nil, VendorNotFoundError
+}
+
+// ChainResolver is a VendorResolver that asks its resolvers in their order until one of them finds the vendor.
+type ChainResolver []VendorResolver
+
+// ResolveVendor implements the VendorResolver interface. If none of the resolvers finds the vendor, the error of the
+// last resolver that failed with an error other than VendorNotFoundError is returned or otherwise VendorNotFoundError.
+func (c ChainResolver) ResolveVendor(hardwareAddr net.HardwareAddr) (*Vendor, error) {
+	err := VendorNotFoundError
+	for _, resolver := range c {
+		v, rErr := resolver.ResolveVendor(hardwareAddr)
+		if rErr == nil {
+			return v, nil
+		} else if rErr != VendorNotFoundError {
+			err = rErr
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMinTimeout is the default lower bound of adaptive timeouts.
+const DefaultMinTimeout = 100 * time.Millisecond
+
+// timeoutEstimator estimates the probe timeout of a single target from the round trip times observed for it.
+// The estimation follows the retransmission timeout calculation of TCP (RFC 6298): the timeout is the smoothed
+// round trip time plus four times its variation, bounded by min and max.
+// A nil pointer to timeoutEstimator is valid and always returns the fallback timeout.
+type timeoutEstimator struct {
+	min, max time.Duration
+
+	mu      sync.Mutex
+	samples int
+	srtt    time.Duration
+	rttVar  time.Duration
+}
+
+// newTimeoutEstimator returns a pointer to a new timeoutEstimator with the bounds min and max.
+// rtts are the initial samples, usually the ping round trip times of the target.
+func newTimeoutEstimator(min, max time.Duration, rtts []time.Duration) *timeoutEstimator {
+	if min > max {
+		min = max
+	}
+	e := &timeoutEstimator{min: min, max: max}
+	for _, rtt := range rtts {
+		e.observe(rtt)
+	}
+	return e
+}
+
+// observe adds the round trip time rtt of a probe that got a response to the estimation.
+func (e *timeoutEstimator) observe(rtt time.Duration) {
+	if e == nil || rtt <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.samples == 0 {
+		e.srtt = rtt
+		e.rttVar = rtt / 2
+	} else {
+		delta := e.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		e.rttVar = (3*e.rttVar + delta) / 4
+		e.srtt = (7*e.srtt + rtt) / 8
+	}
+	e.samples++
+}
+
+// timeout returns the current timeout estimation. Without any samples the upper bound is returned.
+// If the timeoutEstimator pointer is nil, fallback is returned.
+func (e *timeoutEstimator) timeout(fallback time.Duration) time.Duration {
+	if e == nil {
+		return fallback
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.samples == 0 {
+		return e.max
+	}
+	to := e.srtt + 4*e.rttVar
+	if to < e.min {
+		return e.min
+	} else if to > e.max {
+		return e.max
+	}
+	return to
+}
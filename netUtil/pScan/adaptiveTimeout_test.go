@@ -0,0 +1,70 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutEstimator(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name         string
+		min, max     time.Duration
+		rtts         []time.Duration
+		srtt, rttVar time.Duration
+		timeout      time.Duration
+	}{
+		{"no samples", 100 * ms, 3 * time.Second, nil, 0, 0, 3 * time.Second},
+		{"first sample", 100 * ms, 3 * time.Second, []time.Duration{100 * ms}, 100 * ms, 50 * ms, 300 * ms},
+		{"second sample", 100 * ms, 3 * time.Second, []time.Duration{100 * ms, 200 * ms},
+			112500 * time.Microsecond, 62500 * time.Microsecond, 362500 * time.Microsecond},
+		{"constant rtt", 10 * ms, 3 * time.Second, []time.Duration{40 * ms, 40 * ms, 40 * ms},
+			40 * ms, 11250 * time.Microsecond, 85 * ms},
+		{"invalid samples are ignored", 100 * ms, 3 * time.Second, []time.Duration{0, -ms, 100 * ms},
+			100 * ms, 50 * ms, 300 * ms},
+		{"lower bound", 100 * ms, 3 * time.Second, []time.Duration{ms}, ms, 500 * time.Microsecond, 100 * ms},
+		{"upper bound", 100 * ms, 1 * time.Second, []time.Duration{2 * time.Second}, 2 * time.Second, time.Second,
+			time.Second},
+		{"min above max", 5 * time.Second, 1 * time.Second, []time.Duration{ms}, ms, 500 * time.Microsecond,
+			time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTimeoutEstimator(tt.min, tt.max, tt.rtts)
+			if e.srtt != tt.srtt || e.rttVar != tt.rttVar {
+				t.Errorf("srtt, rttVar = %v, %v, want %v, %v", e.srtt, e.rttVar, tt.srtt, tt.rttVar)
+			}
+			if got := e.timeout(time.Hour); got != tt.timeout {
+				t.Errorf("timeout() = %v, want %v", got, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestTimeoutEstimatorNil(t *testing.T) {
+	var e *timeoutEstimator
+	e.observe(time.Millisecond)
+	if got := e.timeout(2 * time.Second); got != 2*time.Second {
+		t.Errorf("timeout() = %v, want the fallback", got)
+	}
+}
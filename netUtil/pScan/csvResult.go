@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/ElCap1tan/gort/netUtil"
+	"io"
+	"strconv"
+)
+
+// csvHeader contains the column names of the CSV output.
+var csvHeader = []string{"ip", "hostname", "port", "proto", "state", "service", "rtt", "product", "version", "reason"}
+
+// CSVWriter writes scan results as CSV with one row per scanned port of every host.
+// The columns are ip, hostname, port, proto, state, service, rtt, product, version and reason where rtt is the
+// average ping round trip time of the host in milliseconds if available. product and version are only filled
+// for ports with a detected service. reason is the PortReason the state of the port was determined by.
+//
+// The header row is written by Start, so a CSVWriter can also be used while streaming results.
+type CSVWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVWriter returns a pointer to a new CSVWriter writing to w.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// Start writes the header row, so that the output is a valid CSV document even if no result is written.
+// It is called by Write if the header row wasn't written yet.
+func (c *CSVWriter) Start() error {
+	if c.headerWritten {
+		return nil
+	}
+	if err := c.w.Write(csvHeader); err != nil {
+		return err
+	}
+	c.headerWritten = true
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Write writes a row for every scanned port of the ScanResult s or a single host row if its Target has no ports.
+func (c *CSVWriter) Write(s *ScanResult) error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	t := s.Target
+	var rtt string
+	if avg := t.AvgRTT(); avg > 0 {
+		rtt = fmt.Sprintf("%.3f", durationToMs(avg))
+	}
+	var err error
+	rows := 0
+	s.Ports.each(func(p *netUtil.Port, state PortState) {
+		service, product, version := p.Service, "", ""
+		if service == "N/A" {
+			service = ""
+		}
+		if d := s.Ports.Detail(p); d != nil && d.Service != nil {
+			if d.Service.Name != "" {
+				service = d.Service.Name
+			}
+			product, version = d.Service.Product, d.Service.Version
+		}
+		if err == nil {
+			err = c.w.Write([]string{
+				t.IPAddr.String(), grepHostName(t), strconv.Itoa(int(p.PortNo)), p.Protocol, state.String(), service, rtt,
+				product, version, s.Ports.Reason(p).String(),
+			})
+		}
+		rows++
+	})
+	if rows == 0 && len(t.Ports) == 0 && err == nil {
+		err = c.w.Write([]string{t.IPAddr.String(), grepHostName(t), "", "", csvHostState(t), "", rtt, "", "", ""})
+	}
+	if err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvHostState returns the state of the host row of the Target pointer t.
+func csvHostState(t *Target) string {
+	if t.CurrentStatus() == Online {
+		return "up"
+	} else if t.Down() {
+		return "down"
+	}
+	return "unknown"
+}
+
+// WriteCSV writes every resolved ScanResult of the MultiScanResult in the CSV format of CSVWriter to w.
+func (m *MultiScanResult) WriteCSV(w io.Writer) error {
+	c := NewCSVWriter(w)
+	if err := c.Start(); err != nil {
+		return err
+	}
+	for _, s := range m.Resolved {
+		if err := c.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
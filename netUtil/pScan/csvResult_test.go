@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	m := newTestMultiScanResult()
+	var buf bytes.Buffer
+	if err := m.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "scan.csv", buf.Bytes())
+}
+
+func TestCSVWriterHeader(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&MultiScanResult{}).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	header := buf.String()
+	if header == "" {
+		t.Fatal("empty scan result has no header row")
+	}
+
+	buf.Reset()
+	c := NewCSVWriter(&buf)
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != header {
+		t.Errorf("Start() wrote %q, want %q", buf.String(), header)
+	}
+	m := newTestMultiScanResult()
+	if err := c.Write(m.Resolved[1]); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte(header)); n != 1 {
+		t.Errorf("header row written %d times, want once", n)
+	}
+}
+
+func TestCSVWriterHostRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		target *Target
+		want   string
+	}{
+		{"up", &Target{IPAddr: net.ParseIP("192.0.2.10"), HostName: "web.example.com.", Status: Online,
+			RTTs: []time.Duration{2 * time.Millisecond}}, "192.0.2.10,web.example.com.,,,up,,2.000,,,\n"},
+		{"down", &Target{IPAddr: net.ParseIP("192.0.2.1"), HostName: "N/A", Status: Unknown, probed: true},
+			"192.0.2.1,,,,down,,,,,\n"},
+		{"discovery skipped", &Target{IPAddr: net.ParseIP("2001:db8::1"), Status: Unknown},
+			"2001:db8::1,,,,unknown,,,,,\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c := NewCSVWriter(&buf)
+			if err := c.Start(); err != nil {
+				t.Fatal(err)
+			}
+			header := buf.String()
+			if err := c.Write(NewScanResult(tt.target, time.Now())); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimPrefix(buf.String(), header); got != tt.want {
+				t.Errorf("host row = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
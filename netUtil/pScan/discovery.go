@@ -0,0 +1,360 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/ElCap1tan/gort/internal/colorFmt"
+	"github.com/ElCap1tan/gort/internal/symbols"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// DiscoveryProbe is an integer representing a technique used by the host discovery to determine if a Target is up.
+// The values can be ICMPEchoProbe, ICMPTimestampProbe, TCPSynProbe, TCPAckProbe, TCPConnectProbe or ARPProbe.
+type DiscoveryProbe int
+
+const (
+	// ICMPEchoProbe sends ICMP echo requests (ping).
+	ICMPEchoProbe DiscoveryProbe = iota
+
+	// ICMPTimestampProbe sends an ICMP timestamp request, which is often answered by hosts that filter echo
+	// requests. It requires root privileges and only supports IPv4 targets.
+	ICMPTimestampProbe
+
+	// TCPSynProbe sends a TCP SYN packet to every port of DiscoveryOptions.SynPorts. Any response means the host is
+	// up. It requires root privileges and the Privileged option and only supports IPv4 targets. Otherwise TCP
+	// connections to the same ports are used instead.
+	TCPSynProbe
+
+	// TCPAckProbe sends a TCP ACK packet to every port of DiscoveryOptions.AckPorts, which is answered with a RST by
+	// hosts that are up. It passes stateless firewalls that only block new connections. It requires root privileges
+	// and the Privileged option and only supports IPv4 targets. Otherwise TCP connections to the same ports are used
+	// instead.
+	TCPAckProbe
+
+	// TCPConnectProbe tries to establish a TCP connection to every port of DiscoveryOptions.ConnectPorts.
+	// An accepted or refused connection means the host is up.
+	TCPConnectProbe
+
+	// ARPProbe sends an ARP-request or for IPv6 targets a NDP neighbor solicitation.
+	// It only applies to targets in a local network.
+	ARPProbe
+)
+
+var discoveryProbeNames = map[DiscoveryProbe]string{
+	ICMPEchoProbe:      "ICMP echo",
+	ICMPTimestampProbe: "ICMP timestamp",
+	TCPSynProbe:        "TCP SYN",
+	TCPAckProbe:        "TCP ACK",
+	TCPConnectProbe:    "TCP connect",
+	ARPProbe:           "ARP",
+}
+
+// DefaultDiscoveryPorts are the ports the TCP discovery probes are sent to if no other ports are specified.
+var DefaultDiscoveryPorts = []uint16{80, 443}
+
+// errProbeNotApplicable is returned by a discovery probe that can't be used for a Target,
+// e.g. an ARP probe of a target outside of the local networks.
+var errProbeNotApplicable = errors.New("probe not applicable")
+
+// Discover performs only the host discovery for every host in hosts without scanning any ports (ping sweep).
+// Every Target is resolved, the discovery probes of opts are sent to it and its MAC-address and vendor are looked up
+// as done by NewTarget. hosts accepts the same formats as ParseHostString and opts can be nil.
+// The returned Targets are sorted by their IP address and also contain the unresolved targets, which come last.
+// Use Target.Down and Target.CurrentStatus to tell which of them are up. If ctx is done while the targets are
+// discovered, the targets discovered so far are returned.
+func Discover(ctx context.Context, hosts string, opts *DiscoveryOptions) Targets {
+	return NewTargetIterator(hosts, false).Discover(ctx, opts)
+}
+
+// Discover works like the Discover function but discovers the remaining hosts of the TargetIterator pointer.
+func (it *TargetIterator) Discover(ctx context.Context, opts *DiscoveryOptions) Targets {
+	var targets Targets
+	for t := range it.Resolve(ctx, nil, opts) {
+		targets = append(targets, t)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		a, b := targets[i].IPAddr.To16(), targets[j].IPAddr.To16()
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return bytes.Compare(a, b) < 0
+	})
+	return targets
+}
+
+// discoveryEnv bundles the resources shared by the host discovery of all targets.
+type discoveryEnv struct {
+	synOnce sync.Once
+	syn     *synScanner
+	synErr  error
+
+	mu     sync.Mutex
+	warned map[DiscoveryProbe]bool
+}
+
+// newDiscoveryEnv returns a pointer to a new discoveryEnv.
+func newDiscoveryEnv() *discoveryEnv {
+	return &discoveryEnv{warned: make(map[DiscoveryProbe]bool)}
+}
+
+// synScanner returns the synScanner used by the TCP SYN and ACK probes of all targets.
+// It is created on first use, so a discovery without these probes doesn't open a raw socket.
+func (e *discoveryEnv) synScanner() (*synScanner, error) {
+	e.synOnce.Do(func() {
+		e.syn, e.synErr = newSynScanner()
+	})
+	return e.syn, e.synErr
+}
+
+// warnOnce prints a warning about probe unless a warning about it has already been printed.
+func (e *discoveryEnv) warnOnce(probe DiscoveryProbe, format string, a ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.warned[probe] {
+		return
+	}
+	e.warned[probe] = true
+	colorFmt.Warnf(format, a...)
+}
+
+// close releases the resources of the discoveryEnv.
+func (e *discoveryEnv) close() {
+	if e.syn != nil {
+		_ = e.syn.close()
+	}
+}
+
+// String returns a string representation of the DiscoveryProbe.
+func (p DiscoveryProbe) String() string {
+	if name, ok := discoveryProbeNames[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// discover sends the discovery probes of opts to the Target pointer in their order until one of them is answered,
+// which marks the Target as Online. If at least one probe could be sent but none was answered, the Target is down.
+// Nothing is sent if opts.SkipDiscovery is set. Probes that can't be sent are skipped with a warning.
+func (t *Target) discover(ctx context.Context, opts *DiscoveryOptions) {
+	if opts.SkipDiscovery {
+		return
+	}
+	sent := false
+	for _, probe := range opts.Probes {
+		up, err := t.sendProbe(ctx, probe, opts)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == errProbeNotApplicable {
+			continue
+		} else if err != nil {
+			opts.env.warnOnce(probe, "%s Can't send %s probes: %s. Skipping them...\n",
+				symbols.INFO, probe, err.Error())
+			continue
+		}
+		sent = true
+		if up {
+			t.updateStatus(Online)
+			break
+		}
+	}
+	t.probed = sent
+}
+
+// needsMacQuery returns true if the MAC address of the Target pointer is still unknown and should be queried after the
+// host discovery. No query is made if opts.SkipDiscovery is set or an ARP or NDP probe was already sent during the
+// discovery, as that request would only be repeated.
+func (t *Target) needsMacQuery(opts *DiscoveryOptions) bool {
+	return t.MACAddr == nil && !opts.SkipDiscovery && !t.macRequested
+}
+
+// sendProbe sends probe to the Target pointer and returns true if it was answered.
+func (t *Target) sendProbe(ctx context.Context, probe DiscoveryProbe, opts *DiscoveryOptions) (bool, error) {
+	switch probe {
+	case ICMPEchoProbe:
+		stats, err := t.ping(ctx, opts)
+		return err == nil && stats.PacketsRecv > 0, err
+	case ICMPTimestampProbe:
+		return t.timestampPing(ctx, opts)
+	case TCPSynProbe, TCPAckProbe:
+		return t.rawTCPPing(ctx, probe, opts)
+	case TCPConnectProbe:
+		return t.connectPing(ctx, opts.ConnectPorts, opts)
+	case ARPProbe:
+		return t.arpPing(ctx, opts)
+	}
+	return false, errProbeNotApplicable
+}
+
+// rawTCPPing sends a TCP SYN or ACK packet, as specified by probe, to every port of opts.SynPorts or opts.AckPorts
+// until one of them is answered. If the discovery isn't privileged, raw packets can't be sent or the Target is an IPv6
+// target, connectPing is used with the same ports instead.
+func (t *Target) rawTCPPing(ctx context.Context, probe DiscoveryProbe, opts *DiscoveryOptions) (bool, error) {
+	ports := opts.SynPorts
+	if probe == TCPAckProbe {
+		ports = opts.AckPorts
+	}
+	if !opts.Privileged || t.IPAddr.To4() == nil {
+		return t.connectPing(ctx, ports, opts)
+	}
+	syn, err := opts.env.synScanner()
+	if err != nil {
+		opts.env.warnOnce(probe, "%s Can't send %s probes: %s. Falling back to TCP connect probes...\n",
+			symbols.INFO, probe, err.Error())
+		return t.connectPing(ctx, ports, opts)
+	}
+	for _, port := range ports {
+		if err = opts.RateLimiter.Wait(ctx); err != nil {
+			return false, err
+		}
+		var state PortState
+		if probe == TCPAckProbe {
+			state, err = syn.ackProbe(ctx, t.IPAddr, port, opts.PingTimeout)
+		} else {
+			state, err = syn.probe(ctx, t.IPAddr, port, opts.PingTimeout)
+		}
+		if err != nil {
+			return false, err
+		}
+		if state == PortOpen || state == PortClosed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// connectPing tries to establish a TCP connection to every port of ports until one of them is either accepted or
+// refused.
+func (t *Target) connectPing(ctx context.Context, ports []uint16, opts *DiscoveryOptions) (bool, error) {
+	for _, port := range ports {
+		if err := opts.RateLimiter.Wait(ctx); err != nil {
+			return false, err
+		}
+		d := net.Dialer{Timeout: opts.PingTimeout}
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(t.IPAddr.String(), strconv.Itoa(int(port))))
+		if err == nil {
+			_ = conn.Close()
+			return true, nil
+		}
+		switch classifyError(err) {
+		case ReasonRefused, ReasonConnReset:
+			return true, nil
+		case ReasonResourceExhausted:
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// arpPing sends an ARP-request or NDP neighbor solicitation to the Target pointer if it is part of a local network.
+func (t *Target) arpPing(ctx context.Context, opts *DiscoveryOptions) (bool, error) {
+	if err := t.requestMac(ctx, opts.RateLimiter); err != nil {
+		return false, err
+	}
+	t.macRequested = true
+	if t.Location != Local {
+		return false, errProbeNotApplicable
+	}
+	return t.MACAddr != nil, nil
+}
+
+// timestampPing sends an ICMP timestamp request to the Target pointer and waits for the reply
+// until opts.PingTimeout is reached or ctx is done.
+func (t *Target) timestampPing(ctx context.Context, opts *DiscoveryOptions) (bool, error) {
+	dst := t.IPAddr.To4()
+	if dst == nil {
+		return false, errProbeNotApplicable
+	}
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(opts.PingTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return false, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Unblocks the pending read
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	// Identifier, sequence number and the originate, receive and transmit timestamps
+	// in milliseconds since midnight UTC
+	id := uint16(rand.Intn(0x10000))
+	body := make([]byte, 16)
+	binary.BigEndian.PutUint16(body[0:2], id)
+	binary.BigEndian.PutUint16(body[2:4], 1)
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	binary.BigEndian.PutUint32(body[4:8], uint32(now.Sub(midnight)/time.Millisecond))
+	msg := icmp.Message{Type: ipv4.ICMPTypeTimestamp, Body: &icmp.RawBody{Data: body}}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return false, err
+	}
+	if err = opts.RateLimiter.Wait(ctx); err != nil {
+		return false, err
+	}
+	if _, err = conn.WriteTo(b, &net.IPAddr{IP: dst}); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if nErr, ok := err.(net.Error); ok && nErr.Timeout() {
+				return false, nil
+			}
+			return false, err
+		}
+		if ipAddr, ok := addr.(*net.IPAddr); !ok || !ipAddr.IP.Equal(dst) {
+			continue
+		}
+		// Type, code, checksum, identifier and sequence number
+		if n >= 8 && buf[0] == byte(ipv4.ICMPTypeTimestampReply) && binary.BigEndian.Uint16(buf[4:6]) == id {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoveryOptionsDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        *DiscoveryOptions
+		wantProbes  []DiscoveryProbe
+		wantSyn     []uint16
+		wantConnect []uint16
+	}{
+		{"nil", nil, []DiscoveryProbe{ICMPEchoProbe}, DefaultDiscoveryPorts, DefaultDiscoveryPorts},
+		{"empty", &DiscoveryOptions{}, []DiscoveryProbe{ICMPEchoProbe}, DefaultDiscoveryPorts, DefaultDiscoveryPorts},
+		{
+			"custom",
+			&DiscoveryOptions{
+				Probes:       []DiscoveryProbe{ARPProbe, TCPSynProbe},
+				SynPorts:     []uint16{22},
+				ConnectPorts: []uint16{8080, 8443},
+			},
+			[]DiscoveryProbe{ARPProbe, TCPSynProbe}, []uint16{22}, []uint16{8080, 8443},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts.withDefaults()
+			if !reflect.DeepEqual(opts.Probes, tt.wantProbes) {
+				t.Errorf("Probes = %v, want %v", opts.Probes, tt.wantProbes)
+			}
+			if !reflect.DeepEqual(opts.SynPorts, tt.wantSyn) {
+				t.Errorf("SynPorts = %v, want %v", opts.SynPorts, tt.wantSyn)
+			}
+			if !reflect.DeepEqual(opts.AckPorts, DefaultDiscoveryPorts) {
+				t.Errorf("AckPorts = %v, want %v", opts.AckPorts, DefaultDiscoveryPorts)
+			}
+			if !reflect.DeepEqual(opts.ConnectPorts, tt.wantConnect) {
+				t.Errorf("ConnectPorts = %v, want %v", opts.ConnectPorts, tt.wantConnect)
+			}
+		})
+	}
+}
+
+func TestDiscoveryProbeString(t *testing.T) {
+	tests := []struct {
+		probe DiscoveryProbe
+		want  string
+	}{
+		{ICMPEchoProbe, "ICMP echo"},
+		{ICMPTimestampProbe, "ICMP timestamp"},
+		{TCPSynProbe, "TCP SYN"},
+		{TCPAckProbe, "TCP ACK"},
+		{TCPConnectProbe, "TCP connect"},
+		{ARPProbe, "ARP"},
+		{DiscoveryProbe(-1), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.probe.String(); got != tt.want {
+			t.Errorf("DiscoveryProbe(%d).String() = %q, want %q", tt.probe, got, tt.want)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	open, closed := listenTCPLoopback(t)
+	tests := []struct {
+		name       string
+		addr       string
+		opts       DiscoveryOptions
+		wantStatus TargetStatus
+		wantDown   bool
+	}{
+		{"connect open", "127.0.0.1", DiscoveryOptions{Probes: []DiscoveryProbe{TCPConnectProbe}, ConnectPorts: []uint16{open}},
+			Online, false},
+		{"connect refused", "127.0.0.1", DiscoveryOptions{Probes: []DiscoveryProbe{TCPConnectProbe}, ConnectPorts: []uint16{closed}},
+			Online, false},
+		// Unprivileged SYN and ACK probes fall back to TCP connections to their own ports
+		{"unprivileged syn", "127.0.0.1", DiscoveryOptions{Probes: []DiscoveryProbe{TCPSynProbe}, SynPorts: []uint16{open}},
+			Online, false},
+		{"unprivileged ack", "127.0.0.1", DiscoveryOptions{Probes: []DiscoveryProbe{TCPAckProbe}, AckPorts: []uint16{closed}},
+			Online, false},
+		// An IPv4 only probe isn't sent to an IPv6 target, so the target can't be down
+		{"not applicable", "::1", DiscoveryOptions{Probes: []DiscoveryProbe{ICMPTimestampProbe}},
+			Unknown, false},
+		{"skipped", "127.0.0.1", DiscoveryOptions{Probes: []DiscoveryProbe{TCPConnectProbe}, ConnectPorts: []uint16{open},
+			SkipDiscovery: true}, Unknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts.withDefaults()
+			opts.env = newDiscoveryEnv()
+			defer opts.env.close()
+			h := &Target{InitialTarget: tt.addr, IPAddr: net.ParseIP(tt.addr), Status: Unknown}
+			h.discover(context.Background(), opts)
+			if got := h.CurrentStatus(); got != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", got, tt.wantStatus)
+			}
+			if got := h.Down(); got != tt.wantDown {
+				t.Errorf("Down() = %t, want %t", got, tt.wantDown)
+			}
+		})
+	}
+}
+
+func TestNeedsMacQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		mac          net.HardwareAddr
+		macRequested bool
+		skip         bool
+		want         bool
+	}{
+		{"unknown", nil, false, false, true},
+		{"known", net.HardwareAddr{0, 0x1b, 0x21, 1, 2, 3}, false, false, false},
+		{"requested by discovery", nil, true, false, false},
+		{"discovery skipped", nil, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Target{MACAddr: tt.mac, macRequested: tt.macRequested}
+			if got := h.needsMacQuery(&DiscoveryOptions{SkipDiscovery: tt.skip}); got != tt.want {
+				t.Errorf("needsMacQuery() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverTargets(t *testing.T) {
+	open, _ := listenTCPLoopback(t)
+	tests := []struct {
+		hosts string
+		want  []string
+	}{
+		{"127.0.0.1", []string{"127.0.0.1"}},
+		{"127.0.0.3,127.0.0.1-2", []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}},
+		{"127.0.0.10,127.0.0.9,::1", []string{"::1", "127.0.0.9", "127.0.0.10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hosts, func(t *testing.T) {
+			opts := &DiscoveryOptions{Probes: []DiscoveryProbe{TCPConnectProbe}, ConnectPorts: []uint16{open}}
+			targets := Discover(context.Background(), tt.hosts, opts)
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.IPAddr.String())
+				if target.Ports != nil {
+					t.Errorf("target %s has ports %v", target.IPAddr, target.Ports)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover(%q) = %v, want %v", tt.hosts, got, tt.want)
+			}
+			// Every IPv4 loopback address either accepts or refuses the connection
+			for _, target := range targets {
+				if target.IPAddr.To4() != nil && target.CurrentStatus() != Online {
+					t.Errorf("target %s has status %v, want %v", target.IPAddr, target.CurrentStatus(), Online)
+				}
+			}
+		})
+	}
+}
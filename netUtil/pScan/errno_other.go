@@ -0,0 +1,44 @@
+// +build !windows
+
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import "syscall"
+
+// errnoReasons maps the system error numbers returned by failed probes to their PortReason.
+var errnoReasons = map[syscall.Errno]PortReason{
+	syscall.ECONNREFUSED:  ReasonRefused,
+	syscall.ETIMEDOUT:     ReasonNoResponse,
+	syscall.EHOSTUNREACH:  ReasonHostUnreachable,
+	syscall.EHOSTDOWN:     ReasonHostUnreachable,
+	syscall.ENETUNREACH:   ReasonNetUnreachable,
+	syscall.ENETDOWN:      ReasonNetUnreachable,
+	syscall.EACCES:        ReasonAdminProhibited,
+	syscall.EPERM:         ReasonAdminProhibited,
+	syscall.ECONNRESET:    ReasonConnReset,
+	syscall.ECONNABORTED:  ReasonConnReset,
+	syscall.EMFILE:        ReasonResourceExhausted,
+	syscall.ENFILE:        ReasonResourceExhausted,
+	syscall.ENOBUFS:       ReasonResourceExhausted,
+	syscall.EAGAIN:        ReasonResourceExhausted,
+	syscall.EADDRNOTAVAIL: ReasonResourceExhausted,
+}
@@ -0,0 +1,60 @@
+// +build windows
+
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import "syscall"
+
+// Windows Sockets error codes that aren't defined by the syscall package.
+// See https://docs.microsoft.com/en-us/windows/win32/winsock/windows-sockets-error-codes-2
+const (
+	wsaEACCES        syscall.Errno = 10013
+	wsaEMFILE        syscall.Errno = 10024
+	wsaEWOULDBLOCK   syscall.Errno = 10035
+	wsaEADDRNOTAVAIL syscall.Errno = 10049
+	wsaENETDOWN      syscall.Errno = 10050
+	wsaENETUNREACH   syscall.Errno = 10051
+	wsaECONNABORTED  syscall.Errno = 10053
+	wsaECONNRESET    syscall.Errno = 10054
+	wsaENOBUFS       syscall.Errno = 10055
+	wsaETIMEDOUT     syscall.Errno = 10060
+	wsaECONNREFUSED  syscall.Errno = 10061
+	wsaEHOSTDOWN     syscall.Errno = 10064
+	wsaEHOSTUNREACH  syscall.Errno = 10065
+)
+
+// errnoReasons maps the system error numbers returned by failed probes to their PortReason.
+var errnoReasons = map[syscall.Errno]PortReason{
+	wsaECONNREFUSED:  ReasonRefused,
+	wsaETIMEDOUT:     ReasonNoResponse,
+	wsaEHOSTUNREACH:  ReasonHostUnreachable,
+	wsaEHOSTDOWN:     ReasonHostUnreachable,
+	wsaENETUNREACH:   ReasonNetUnreachable,
+	wsaENETDOWN:      ReasonNetUnreachable,
+	wsaEACCES:        ReasonAdminProhibited,
+	wsaECONNRESET:    ReasonConnReset,
+	wsaECONNABORTED:  ReasonConnReset,
+	wsaEMFILE:        ReasonResourceExhausted,
+	wsaENOBUFS:       ReasonResourceExhausted,
+	wsaEWOULDBLOCK:   ReasonResourceExhausted,
+	wsaEADDRNOTAVAIL: ReasonResourceExhausted,
+}
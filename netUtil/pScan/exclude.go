@@ -0,0 +1,152 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"github.com/ElCap1tan/gort/internal/colorFmt"
+	"github.com/ElCap1tan/gort/internal/helper"
+	"github.com/ElCap1tan/gort/internal/symbols"
+	"net"
+	"strings"
+)
+
+// ExcludeList contains the hosts that must never be touched by a scan.
+// A nil pointer to ExcludeList is valid and excludes nothing.
+type ExcludeList struct {
+	nets   []*net.IPNet
+	ranges []addrRange
+	names  map[string]bool
+}
+
+// addrRange is a range of IP addresses as described by helper.IPRangeSegments. An address is part of the range
+// if every one of its segments is between the lower and upper bound of the segment.
+type addrRange struct {
+	lower, upper []int
+}
+
+// NewExcludeList returns a pointer to a new ExcludeList containing the hosts in specs. Every element of specs is a
+// host specification in the formats accepted by ParseHostString and empty elements are ignored. Host names are excluded by name as well as by the
+// addresses they resolve to, so a host can't be reached over an address given in another format.
+// The names are resolved when NewExcludeList is called and the lookups are aborted when ctx is done.
+func NewExcludeList(ctx context.Context, specs []string) *ExcludeList {
+	e := &ExcludeList{names: make(map[string]bool)}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(spec); err == nil {
+			e.nets = append(e.nets, ipNet)
+		} else if ip := net.ParseIP(spec); ip != nil {
+			e.addIP(ip)
+		} else if segments, ok := helper.IPRangeSegments(spec); ok {
+			r := addrRange{}
+			for _, seg := range segments {
+				r.lower = append(r.lower, seg[0])
+				r.upper = append(r.upper, seg[len(seg)-1])
+			}
+			e.ranges = append(e.ranges, r)
+		} else {
+			e.names[normalizeHostName(spec)] = true
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, spec)
+			if err != nil {
+				colorFmt.Warnf("%s Can't resolve excluded host '%s'. It is only excluded by name...\n", symbols.INFO, spec)
+				continue
+			}
+			for _, ip := range ips {
+				e.addIP(ip.IP)
+			}
+		}
+	}
+	return e
+}
+
+// addIP adds the single address ip to the ExcludeList.
+func (e *ExcludeList) addIP(ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		e.nets = append(e.nets, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+	} else {
+		e.nets = append(e.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+	}
+}
+
+// Excludes returns true if the ExcludeList contains addr, which is either an IP address or a host name.
+func (e *ExcludeList) Excludes(addr string) bool {
+	if e == nil {
+		return false
+	}
+	if ip := net.ParseIP(addr); ip != nil {
+		return e.ExcludesIP(ip)
+	}
+	return e.names[normalizeHostName(addr)]
+}
+
+// ExcludesIP returns true if the ExcludeList contains ip.
+func (e *ExcludeList) ExcludesIP(ip net.IP) bool {
+	if e == nil || ip == nil {
+		return false
+	}
+	for _, ipNet := range e.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	for _, r := range e.ranges {
+		if r.contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludesTarget returns true if the ExcludeList contains the initial address or the resolved IP address of t.
+func (e *ExcludeList) excludesTarget(t *Target) bool {
+	return e.Excludes(t.InitialTarget) || e.ExcludesIP(t.IPAddr)
+}
+
+// contains returns true if ip is part of the addrRange.
+func (r addrRange) contains(ip net.IP) bool {
+	var segments []int
+	if ip4 := ip.To4(); ip4 != nil && len(r.lower) == 4 {
+		for _, b := range ip4 {
+			segments = append(segments, int(b))
+		}
+	} else if ip4 == nil && len(r.lower) == 8 {
+		ip16 := ip.To16()
+		for i := 0; i < 16; i += 2 {
+			segments = append(segments, int(ip16[i])<<8|int(ip16[i+1]))
+		}
+	} else {
+		return false
+	}
+	for i, s := range segments {
+		if s < r.lower[i] || s > r.upper[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeHostName returns the canonical form of the host name name, which is lower case and without trailing dot.
+func normalizeHostName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
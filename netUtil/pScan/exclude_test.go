@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestExcludeList(t *testing.T) {
+	e := NewExcludeList(context.Background(), []string{
+		"10.0.0.0/24", " 192.168.1.5 ", "172.16.1-2.10-20", "2001:db8::/126", "", "LocalHost",
+	})
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.0.0.0", true},
+		{"10.0.0.255", true},
+		{"10.0.1.0", false},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"172.16.1.10", true},
+		{"172.16.2.20", true},
+		{"172.16.2.21", false},
+		{"172.16.3.15", false},
+		{"2001:db8::3", true},
+		{"2001:db8::4", false},
+		{"::ffff:10.0.0.1", true},
+		{"localhost", true},
+		{"LOCALHOST.", true},
+		{"127.0.0.1", true},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := e.Excludes(tt.addr); got != tt.want {
+			t.Errorf("Excludes(%q) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNilExcludeList(t *testing.T) {
+	var e *ExcludeList
+	if e.Excludes("10.0.0.1") || e.ExcludesIP(net.ParseIP("10.0.0.1")) {
+		t.Error("nil ExcludeList excludes an address")
+	}
+}
+
+func TestExcludedTargetIsNotResolved(t *testing.T) {
+	tests := []struct {
+		exclude, addr string
+	}{
+		{"127.0.0.0/8", "127.0.0.1"},
+		{"127.0.0.0/8", "localhost"},
+		// Host names excluded by their address are resolved, but their reverse lookup is skipped.
+		{"127.0.0.1", "localhost"},
+	}
+	for _, tt := range tests {
+		opts := &DiscoveryOptions{Exclude: NewExcludeList(context.Background(), []string{tt.exclude})}
+		target := NewTarget(context.Background(), tt.addr, nil, opts)
+		if !target.Excluded() {
+			t.Errorf("target %s isn't excluded by %s", tt.addr, tt.exclude)
+		}
+		if target.HostName != "" {
+			t.Errorf("host name of target %s excluded by %s was looked up: %s", tt.addr, tt.exclude, target.HostName)
+		}
+	}
+}
+
+func TestTargetIteratorSkipsExcluded(t *testing.T) {
+	opts := &DiscoveryOptions{Exclude: NewExcludeList(context.Background(), []string{"127.0.0.2-3"}),
+		SkipDiscovery: true}
+	var got []string
+	for target := range NewTargetIterator("127.0.0.1-4", false).Resolve(context.Background(), nil, opts) {
+		got = append(got, target.IPAddr.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("got targets %v, want 127.0.0.1 and 127.0.0.4", got)
+	}
+	for _, addr := range got {
+		if addr != "127.0.0.1" && addr != "127.0.0.4" {
+			t.Errorf("excluded target %s was resolved", addr)
+		}
+	}
+}
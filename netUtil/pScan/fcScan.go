@@ -22,129 +22,462 @@ package pScan
 
 import (
 	"context"
-	"github.com/ElCap1tan/gort/internal/helper/ulimit"
+	"github.com/ElCap1tan/gort/internal/colorFmt"
+	"github.com/ElCap1tan/gort/internal/symbols"
 	"github.com/ElCap1tan/gort/netUtil"
 	"net"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
+)
+
+// PortState is an integer representing the state of a scanned port.
+// The values can be PortOpen, PortClosed, PortFiltered, PortOpenFiltered or PortError.
+type PortState int
+
+const (
+	PortOpen PortState = iota
+	PortClosed
+	PortFiltered
+
+	// PortOpenFiltered is the state of UDP ports that didn't respond at all. They are either open or filtered.
+	PortOpenFiltered
 
-	"golang.org/x/sync/semaphore"
+	// PortError is the state of ports whose state couldn't be determined because of an error.
+	PortError
 )
 
-// Scan performs a concurrent full connection scan for every Target in Targets and once finished,
-// returns the scan result as an MultiScanResult.
-func (t Targets) Scan() MultiScanResult {
-	var multiScanRes MultiScanResult
-	out := make(chan *ScanResult)
+// String returns a string representation of PortState.
+func (ps PortState) String() string {
+	if ps == PortOpen {
+		return "open"
+	} else if ps == PortClosed {
+		return "closed"
+	} else if ps == PortFiltered {
+		return "filtered"
+	} else if ps == PortOpenFiltered {
+		return "open|filtered"
+	} else if ps == PortError {
+		return "error"
+	}
+	return "N/A"
+}
 
-	var limit int64
-	l, err := ulimit.GetUlimit()
-	if err != nil {
-		limit = 1024
-	} else {
-		limit = int64(l)
+// id returns a short identifier of PortState that can be used as CSS class name.
+func (ps PortState) id() string {
+	if ps == PortOpenFiltered {
+		return "open-filtered"
+	}
+	return ps.String()
+}
+
+// nmapString returns the port state as used by nmap. Nmap has no state for errored ports,
+// so they are reported as filtered.
+func (ps PortState) nmapString() string {
+	if ps == PortError {
+		return PortFiltered.String()
+	}
+	return ps.String()
+}
+
+// PortEvent represents the outcome of the scan of a single port of a Target.
+type PortEvent struct {
+	// Target is the scanned Target.
+	Target *Target
+
+	// Port is the scanned port.
+	Port *netUtil.Port
+
+	// State is the PortState the port was determined to be in.
+	State PortState
+
+	// Reason is the reason the port was determined to be in its state.
+	Reason PortReason
+
+	// Detail contains the additional information gathered for the port or nil if there is none.
+	Detail *PortDetail
+
+	// Time is the time the state of the port was determined at.
+	Time time.Time
+}
+
+// ScanTechnique is an integer representing the technique used to determine the state of TCP ports.
+// The values can be ConnectScan or SynScan.
+type ScanTechnique int
+
+const (
+	// ConnectScan determines the port state by establishing a full TCP connection.
+	ConnectScan ScanTechnique = iota
+
+	// SynScan determines the port state by sending TCP SYN packets over a raw socket without ever completing
+	// the handshake (half-open scan). It is only supported on Linux and requires root privileges.
+	// For IPv6 targets or if the raw socket can't be opened ConnectScan is used instead.
+	SynScan
+)
+
+// ScanOptions contains the optional settings of a scan.
+// A nil pointer to ScanOptions is valid and results in a scan with the default settings.
+type ScanOptions struct {
+	// OnPort is called for every scanned port as soon as its state is known.
+	// It is called concurrently for different targets and thus must be safe for concurrent use.
+	OnPort func(e *PortEvent)
+
+	// HostTimeout is the maximum duration the scan of a single Target may take. Ports that are not scanned
+	// when the timeout is reached are reported as errored. If HostTimeout is zero there is no per-host limit.
+	HostTimeout time.Duration
+
+	// SkipDown disables the port scan of targets the host discovery determined to be down (see Target.Down).
+	// Their ScanResult contains no ports.
+	SkipDown bool
+
+	// Technique is the ScanTechnique used to scan TCP ports. Defaults to ConnectScan.
+	Technique ScanTechnique
+
+	// ServiceDetection enables the detection of the services running on open TCP ports by reading their banners
+	// and sending protocol specific probes. The detected services are stored in the PortDetail of the port.
+	ServiceDetection bool
+
+	// TLSInspection enables a TLS handshake with open TCP ports. For ports that speak TLS the negotiated
+	// parameters and the server certificate are stored in the PortDetail of the port.
+	TLSInspection bool
+
+	// CertExpiryWarning is the time before the expiry of a certificate it is flagged as expiring soon.
+	// Defaults to DefaultCertExpiryWarning.
+	CertExpiryWarning time.Duration
+
+	// Concurrency is the maximum number of ports that are scanned simultaneously over all targets, which is
+	// the size of the worker pool of the scan. If Concurrency is zero the limit is the maximum number of open files
+	// allowed by the system.
+	Concurrency int
+
+	// MaxHosts is the maximum number of targets that are scanned simultaneously. If MaxHosts is zero the number
+	// is only limited by the worker pool, which takes on the ports of the targets one target after another.
+	MaxHosts int
+
+	// RateLimiter limits the rate of the probes sent to the ports. It can be shared with DiscoveryOptions to limit
+	// the overall rate of the scan. If RateLimiter is nil the rate isn't limited.
+	RateLimiter *RateLimiter
+
+	// Timeout is the time to wait for the response to a single probe before a port is considered filtered.
+	// Defaults to 3 seconds.
+	Timeout time.Duration
+
+	// Retries is the number of times a port is probed again if there was no response to the probe
+	// or the probe failed with a transient error.
+	Retries int
+
+	// AdaptiveTimeout enables a separate probe timeout for every target that is derived from the ping round trip
+	// times of the target and the latencies of the responses observed while it is scanned. The timeouts are
+	// bounded by MinTimeout and MaxTimeout and replace Timeout.
+	AdaptiveTimeout bool
+
+	// MinTimeout is the lower bound of adaptive timeouts. Defaults to DefaultMinTimeout.
+	MinTimeout time.Duration
+
+	// MaxTimeout is the upper bound of adaptive timeouts, which is also used until the first response is observed.
+	// Defaults to Timeout.
+	MaxTimeout time.Duration
+}
+
+// scanEnv bundles the settings and resources shared by the scans of all targets of a single scan.
+type scanEnv struct {
+	opts *ScanOptions
+	syn  *synScanner
+
+	udpOnce sync.Once
+	udp     *udpScanner
+}
+
+// newScanEnv returns a pointer to a new scanEnv for a scan with the settings in opts, which may be nil.
+// If a SYN scan is requested but not possible, a warning is printed and the scan falls back to a connect scan.
+func newScanEnv(opts *ScanOptions) *scanEnv {
+	o := ScanOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 3000 * time.Millisecond
+	}
+	if o.MinTimeout <= 0 {
+		o.MinTimeout = DefaultMinTimeout
+	}
+	if o.MaxTimeout <= 0 {
+		o.MaxTimeout = o.Timeout
+	}
+	opts = &o
+	env := &scanEnv{opts: opts}
+	if opts.Technique == SynScan {
+		syn, err := newSynScanner()
+		if err != nil {
+			colorFmt.Warnf("%s Can't start SYN scan: %s. Falling back to connect scan...\n", symbols.INFO, err.Error())
+		} else {
+			env.syn = syn
+		}
+	}
+	return env
+}
+
+// close releases the resources of the scanEnv.
+func (e *scanEnv) close() {
+	if e.syn != nil {
+		_ = e.syn.close()
+	}
+	if e.udp != nil {
+		_ = e.udp.close()
 	}
+}
 
-	lock := semaphore.NewWeighted(limit)
+// udpScanner returns the udpScanner shared by all targets of the scan.
+// It is created on first use, so scans without UDP ports don't open an ICMP listener.
+func (e *scanEnv) udpScanner() *udpScanner {
+	e.udpOnce.Do(func() {
+		e.udp = newUDPScanner()
+	})
+	return e.udp
+}
+
+// Scan performs a concurrent port scan for every Target in Targets and once finished,
+// returns the scan result as an MultiScanResult. opts controls the optional settings of the scan and can be nil.
+// If ctx is done before the scan is finished, the partial results of the ports scanned so far are returned.
+func (t Targets) Scan(ctx context.Context, opts *ScanOptions) MultiScanResult {
+	var multiScanRes MultiScanResult
 	for _, t := range t {
-		if t.IPAddr == nil {
+		if t.IPAddr == nil && !t.Excluded() {
 			multiScanRes.Unresolved = append(multiScanRes.Unresolved, t)
-		} else {
-			go t.scan(out, lock)
 		}
 	}
-	for i := 0; i < len(t)-len(multiScanRes.Unresolved); i++ {
-		multiScanRes.Resolved = append(multiScanRes.Resolved, <-out)
+	for r := range t.ScanStream(ctx, opts) {
+		multiScanRes.Resolved = append(multiScanRes.Resolved, r)
 	}
 	return multiScanRes
 }
 
-// Scan performs a concurrent full connection scan for all ports of a singe Target and
-// returns a pointer to the ScanResult when finished.
-func (t *Target) Scan() *ScanResult {
-	r := NewScanResult(t, time.Now())
-	ch := make(chan *PortResult)
+// ScanStream starts a concurrent port scan for every resolved Target in Targets and returns a channel
+// over which the ScanResult of every Target is sent as soon as its scan is finished. Unresolved and excluded targets
+// are skipped.
+// opts controls the optional settings of the scan and can be nil.
+// The channel is closed once all targets are scanned. If ctx is done, the remaining ports aren't scanned anymore and
+// the partial results of all targets are sent, so the channel always has to be drained.
+func (t Targets) ScanStream(ctx context.Context, opts *ScanOptions) <-chan *ScanResult {
+	out := make(chan *ScanResult)
+	i := 0
+	next := func() *Target {
+		for i < len(t) {
+			target := t[i]
+			i++
+			if target.IPAddr != nil && !target.Excluded() {
+				return target
+			}
+		}
+		return nil
+	}
+	go newScanEngine(newScanEnv(opts)).run(ctx, next, out)
+	return out
+}
 
-	var limit int64
-	l, err := ulimit.GetUlimit()
-	if err != nil {
-		limit = 1024
-	} else {
-		limit = int64(l)
+// ScanTargetStream starts a concurrent port scan for every resolved Target received over targets and returns
+// a channel over which the ScanResult of every Target is sent as soon as its scan is finished. The targets are
+// scanned as they are received, so a scan fed by TargetIterator.Resolve runs as a pipeline together with the host
+// discovery. Unresolved targets are passed to unresolved, which can be nil, and excluded targets are skipped.
+// opts controls the optional settings of the scan and can be nil.
+// The channel is closed once targets is closed and all targets are scanned. If ctx is done, the remaining ports
+// aren't scanned anymore and the partial results of all targets are sent, so the channel always has to be drained.
+func ScanTargetStream(ctx context.Context, targets <-chan *Target, opts *ScanOptions, unresolved func(t *Target)) <-chan *ScanResult {
+	out := make(chan *ScanResult)
+	next := func() *Target {
+		for target := range targets {
+			if target.Excluded() {
+				continue
+			}
+			if target.IPAddr != nil {
+				return target
+			}
+			if unresolved != nil {
+				unresolved(target)
+			}
+		}
+		return nil
+	}
+	go newScanEngine(newScanEnv(opts)).run(ctx, next, out)
+	return out
+}
+
+// Scan performs a concurrent port scan for all ports of a singe Target and
+// returns a pointer to the ScanResult when finished. opts controls the optional settings of the scan and can be nil.
+// If ctx is done before the scan is finished, the partial result of the ports scanned so far is returned.
+func (t *Target) Scan(ctx context.Context, opts *ScanOptions) *ScanResult {
+	out := make(chan *ScanResult, 1)
+	target := t
+	next := func() *Target {
+		next := target
+		target = nil
+		return next
+	}
+	newScanEngine(newScanEnv(opts)).run(ctx, next, out)
+	return <-out
+}
+
+// scanPort scans a single port of the Target as specified by p and returns the result.
+// The RateLimiter of env is used to control the rate of the probes. Ports without response or with a transient error are probed again up to the
+// configured number of retries. If estimator isn't nil, it provides the probe timeout and the latencies of all
+// responses are added to it.
+// The result always contains p exactly once. If the state of the port couldn't be determined, because of an error
+// or because ctx is done, p is added to the errored ports and the error is recorded in the PortDetail of p.
+func (t *Target) scanPort(ctx context.Context, p *netUtil.Port, env *scanEnv, estimator *timeoutEstimator) *PortResult {
+	res := NewPortResult()
+	var (
+		reason   PortReason
+		conn     net.Conn
+		err      error
+		attempts int
+		timeOut  time.Duration
+	)
+	for retries := 0; ; {
+		if err = env.opts.RateLimiter.Wait(ctx); err != nil {
+			reason = ReasonCanceled
+			break
+		}
+		timeOut = estimator.timeout(env.opts.Timeout)
+		attempts++
+		start := time.Now()
+		reason, conn, err = t.probePort(ctx, p, env, timeOut)
+		if state := reason.State(p.Protocol); state == PortOpen || state == PortClosed {
+			estimator.observe(time.Since(start))
+			break
+		}
+		if ctxExpired(ctx) {
+			// A missing response or an error is caused by the cancellation and not by the port.
+			reason, err = ReasonCanceled, ctxErr(ctx)
+			break
+		}
+		if reason == ReasonResourceExhausted {
+			// The port isn't to blame for missing resources, so the probe is repeated without counting as retry
+			// once the other workers had the chance to release some.
+			select {
+			case <-time.After(timeOut):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		if retries < env.opts.Retries && reason.transient() {
+			retries++
+			continue
+		}
+		break
 	}
 
-	lock := semaphore.NewWeighted(limit)
-	for _, p := range t.Ports {
-		go t.scanPort(p, ch, lock)
+	if state := reason.State(p.Protocol); state == PortError {
+		res.addError(p, reason, err, attempts)
+	} else {
+		switch state {
+		case PortOpen:
+			t.updateStatus(Online)
+			res.Open = append(res.Open, p)
+			if p.Protocol == "tcp" {
+				t.inspectOpenPort(ctx, p, conn, res, env, timeOut)
+			}
+		case PortClosed:
+			t.updateStatus(Online)
+			res.Closed = append(res.Closed, p)
+		case PortFiltered:
+			t.updateStatus(OfflineFiltered)
+			res.Filtered = append(res.Filtered, p)
+		case PortOpenFiltered:
+			res.OpenFiltered = append(res.OpenFiltered, p)
+		}
+		d := res.detail(p)
+		d.Reason = reason
+		if attempts > 1 {
+			d.Attempts = attempts
+		}
 	}
-	for range t.Ports {
-		pI := <-ch
-		r.Ports.Open = append(r.Ports.Open, pI.Open...)
-		r.Ports.Closed = append(r.Ports.Closed, pI.Closed...)
-		r.Ports.Filtered = append(r.Ports.Filtered, pI.Filtered...)
+	if conn != nil {
+		_ = conn.Close()
 	}
-	r.EndTime = time.Now()
-	return r
+	return res
 }
 
-// scan starts a concurrent full connection port scan for every port of the Target and writes the results to out.
-// The lock can be used to control how many concurrent scans are allowed to run.
-func (t *Target) scan(out chan *ScanResult, lock *semaphore.Weighted) {
-	r := NewScanResult(t, time.Now())
-	ch := make(chan *PortResult)
-	for _, p := range t.Ports {
-		go t.scanPort(p, ch, lock)
+// probePort sends a single probe to port p of the Target and returns the PortReason that determines the state of
+// the port. UDP ports are probed by the udpScanner of env and TCP ports either by its synScanner or with a full
+// connection. If a full connection was established, it is returned as well and must be closed by the caller.
+// If no response was received within timeOut, ReasonNoResponse is returned. The error the probe failed with is
+// returned together with its classification.
+func (t *Target) probePort(ctx context.Context, p *netUtil.Port, env *scanEnv, timeOut time.Duration) (PortReason, net.Conn, error) {
+	if p.Protocol == "udp" {
+		reason, err := env.udpScanner().probe(ctx, t.IPAddr, p.PortNo, timeOut)
+		return reason, nil, err
 	}
-	for range t.Ports {
-		pI := <-ch
-		r.Ports.Open = append(r.Ports.Open, pI.Open...)
-		r.Ports.Closed = append(r.Ports.Closed, pI.Closed...)
-		r.Ports.Filtered = append(r.Ports.Filtered, pI.Filtered...)
+	if env.syn != nil && t.IPAddr.To4() != nil {
+		state, err := env.syn.probe(ctx, t.IPAddr, p.PortNo, timeOut)
+		if err != nil {
+			return classifyError(err), nil, err
+		}
+		switch state {
+		case PortOpen:
+			return ReasonSynAck, nil, nil
+		case PortClosed:
+			return ReasonReset, nil, nil
+		}
+		return ReasonNoResponse, nil, nil
 	}
-	r.EndTime = time.Now()
-	out <- r
+	dialer := net.Dialer{Timeout: timeOut}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(t.IPAddr.String(), strconv.Itoa(int(p.PortNo))))
+	if err != nil {
+		return classifyError(err), nil, err
+	}
+	return ReasonSynAck, conn, nil
 }
 
-// scanPort scans a single port of the Target as specified by p. When finished the result is written to ch.
-// The parameter lock can be used to control how many concurrent scans are allowed to run.
-func (t *Target) scanPort(p *netUtil.Port, ch chan *PortResult, lock *semaphore.Weighted) {
-	res := NewPortResult()
-	milli := 3000
-	timeOut := time.Duration(milli) * time.Millisecond
-	lock.Acquire(context.TODO(), 1)
-	conn, err := net.DialTimeout("tcp", t.IPAddr.String()+":"+strconv.Itoa(int(p.PortNo)), timeOut)
-	if err == nil {
-		defer conn.Close()
-		t.Status = Online
-		res.Open = append(res.Open, p)
-		ch <- res
-		lock.Release(1)
-		return
-	} else if _, ok := err.(*net.OpError); ok {
-		if t.Status == Unknown || t.Status == OfflineFiltered {
-			if strings.HasSuffix(err.Error(), "No connection could be made because the target machine actively refused it.") ||
-				strings.HasSuffix(err.Error(), "connect: connection refused") {
-				t.Status = Online
-			} else if strings.HasSuffix(err.Error(), "i/o timeout") && t.Status == Unknown {
-				t.Status = OfflineFiltered
+// inspectOpenPort runs the enabled service detection and TLS inspection for the open TCP port p of the Target and
+// stores the results in the PortDetail of p in res. conn is an established connection to the port that is used
+// to read the banner of the service. If conn is nil a new connection is established.
+func (t *Target) inspectOpenPort(ctx context.Context, p *netUtil.Port, conn net.Conn, res *PortResult, env *scanEnv, timeOut time.Duration) {
+	opts := env.opts
+	if opts.ServiceDetection {
+		if conn == nil {
+			dialer := net.Dialer{Timeout: timeOut}
+			c, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(t.IPAddr.String(), strconv.Itoa(int(p.PortNo))))
+			if err == nil {
+				defer c.Close()
+				conn = c
 			}
 		}
-		if strings.HasSuffix(err.Error(), "No connection could be made because the target machine actively refused it.") ||
-			strings.HasSuffix(err.Error(), "connect: connection refused") {
-			res.Closed = append(res.Closed, p)
+		if conn != nil {
+			if svc := t.detectService(ctx, conn, p); svc != nil {
+				res.detail(p).Service = svc
+			}
 		}
-		if strings.HasSuffix(err.Error(), "i/o timeout") {
-			res.Filtered = append(res.Filtered, p)
+	}
+	if opts.TLSInspection {
+		expiryWarning := opts.CertExpiryWarning
+		if expiryWarning == 0 {
+			expiryWarning = DefaultCertExpiryWarning
+		}
+		handshakeTimeout := tlsHandshakeTimeout
+		if timeOut > handshakeTimeout {
+			handshakeTimeout = timeOut
 		}
-		if strings.HasSuffix(err.Error(), "too many open files") {
-			time.Sleep(timeOut)
-			go t.scanPort(p, ch, lock)
-			// TODO Check if it makes sense to not release the lock if to many files are open already
-			lock.Release(1)
-			return
+		if ti := t.inspectTLS(ctx, p, handshakeTimeout, expiryWarning); ti != nil {
+			res.detail(p).TLS = ti
 		}
 	}
-	lock.Release(1)
-	ch <- res
+}
+
+// ctxErr returns the error of ctx. Other than ctx.Err() it also reports a reached deadline before the timer of ctx
+// has fired.
+func ctxErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return context.DeadlineExceeded
+}
+
+// ctxExpired returns true if ctx is done or its deadline is reached.
+// Other than checking ctx.Err() it also reports a reached deadline before the timer of ctx has fired.
+func ctxExpired(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	d, ok := ctx.Deadline()
+	return ok && !time.Now().Before(d)
 }
@@ -0,0 +1,187 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"github.com/ElCap1tan/gort/netUtil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scanSinglePort scans port p of a loopback target with the settings in opts and returns the PortResult.
+func scanSinglePort(t *testing.T, ctx context.Context, p *netUtil.Port, opts *ScanOptions) *PortResult {
+	t.Helper()
+	env := newScanEnv(opts)
+	defer env.close()
+	target := &Target{InitialTarget: "127.0.0.1", IPAddr: net.IPv4(127, 0, 0, 1), Ports: netUtil.Ports{p}}
+	res := target.scanPort(ctx, p, env, nil)
+	n := 0
+	res.each(func(*netUtil.Port, PortState) { n++ })
+	if n != 1 {
+		t.Fatalf("port is contained %d times in the result, want exactly once", n)
+	}
+	return res
+}
+
+func TestScanPortRetries(t *testing.T) {
+	open, closed := listenTCPLoopback(t)
+	tests := []struct {
+		name     string
+		port     *netUtil.Port
+		retries  int
+		state    PortState
+		attempts int
+	}{
+		{"open", netUtil.NewPort(open, "tcp", "N/A", "N/A"), 2, PortOpen, 0},
+		{"closed", netUtil.NewPort(closed, "tcp", "N/A", "N/A"), 2, PortClosed, 0},
+		// Ports without response are probed again.
+		{"no response", netUtil.NewPort(listenUDPLoopback(t, false), "udp", "N/A", "N/A"), 2, PortOpenFiltered, 3},
+		{"no retries", netUtil.NewPort(listenUDPLoopback(t, false), "udp", "N/A", "N/A"), 0, PortOpenFiltered, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := scanSinglePort(t, context.Background(), tt.port,
+				&ScanOptions{Timeout: 100 * time.Millisecond, Retries: tt.retries})
+			res.each(func(_ *netUtil.Port, state PortState) {
+				if state != tt.state {
+					t.Errorf("state = %v, want %v", state, tt.state)
+				}
+			})
+			attempts := 0
+			if d := res.Detail(tt.port); d != nil {
+				attempts = d.Attempts
+			}
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestScanPortCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := netUtil.NewPort(listenUDPLoopback(t, false), "udp", "N/A", "N/A")
+	res := scanSinglePort(t, ctx, p, &ScanOptions{Timeout: 100 * time.Millisecond, Retries: 2})
+	if len(res.Errored) != 1 {
+		t.Fatalf("errored ports = %v, want [%v]", res.Errored, p)
+	}
+	if err := res.Detail(p).Err; err != context.Canceled {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// listenTCPLoopback returns a free loopback port with a listener that accepts and closes all connections and a
+// port that refuses connections. The listener is closed when the test is finished.
+func listenTCPLoopback(t *testing.T) (uint16, uint16) {
+	t.Helper()
+	ln := serveTCPLoopback(t, func(net.Conn) {})
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := uint16(closed.Addr().(*net.TCPAddr).Port)
+	_ = closed.Close()
+	return uint16(ln.Addr().(*net.TCPAddr).Port), closedPort
+}
+
+// newLoopbackTarget returns a pointer to a resolved loopback Target with the given TCP ports.
+func newLoopbackTarget(ports ...uint16) *Target {
+	var ps netUtil.Ports
+	for _, p := range ports {
+		ps = append(ps, netUtil.NewPort(p, "tcp", "N/A", "N/A"))
+	}
+	return &Target{InitialTarget: "127.0.0.1", IPAddr: net.ParseIP("127.0.0.1"), Ports: ps, Status: Unknown}
+}
+
+// assertPorts fails the test if the ports of r aren't exactly the open and closed ports.
+func assertPorts(t *testing.T, r *ScanResult, open, closed uint16) {
+	t.Helper()
+	if len(r.Ports.Open) != 1 || r.Ports.Open[0].PortNo != open {
+		t.Errorf("open ports = %v, want [%d/tcp]", r.Ports.Open, open)
+	}
+	if len(r.Ports.Closed) != 1 || r.Ports.Closed[0].PortNo != closed {
+		t.Errorf("closed ports = %v, want [%d/tcp]", r.Ports.Closed, closed)
+	}
+	if n := len(r.Ports.Filtered) + len(r.Ports.OpenFiltered) + len(r.Ports.Errored); n != 0 {
+		t.Errorf("%d ports are neither open nor closed", n)
+	}
+	if r.Technique != ConnectScan {
+		t.Errorf("technique = %d, want ConnectScan", r.Technique)
+	}
+}
+
+func TestScanLoopback(t *testing.T) {
+	open, closed := listenTCPLoopback(t)
+	opts := &ScanOptions{Timeout: time.Second, Concurrency: 8}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		multi  MultiScanResult
+		single *ScanResult
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		multi = Targets{newLoopbackTarget(open, closed), newLoopbackTarget(closed, open)}.Scan(ctx, opts)
+	}()
+	go func() {
+		defer wg.Done()
+		single = newLoopbackTarget(open, closed).Scan(ctx, opts)
+	}()
+	wg.Wait()
+
+	if len(multi.Resolved) != 2 || len(multi.Unresolved) != 0 {
+		t.Fatalf("got %d resolved and %d unresolved results, want 2 and 0", len(multi.Resolved), len(multi.Unresolved))
+	}
+	for _, r := range multi.Resolved {
+		assertPorts(t, r, open, closed)
+	}
+	assertPorts(t, single, open, closed)
+	if reason := single.Ports.Reason(single.Ports.Open[0]); reason != ReasonSynAck {
+		t.Errorf("reason of open port = %s, want %s", reason, ReasonSynAck)
+	}
+	if reason := single.Ports.Reason(single.Ports.Closed[0]); reason != ReasonRefused {
+		t.Errorf("reason of closed port = %s, want %s", reason, ReasonRefused)
+	}
+}
+
+func TestScanSkipsUnresolvedAndExcluded(t *testing.T) {
+	open, closed := listenTCPLoopback(t)
+	excluded := newLoopbackTarget(open, closed)
+	excluded.excluded = true
+	unresolved := &Target{InitialTarget: "unresolved.invalid", Status: OfflineFiltered}
+
+	res := Targets{newLoopbackTarget(open, closed), excluded, unresolved}.Scan(context.Background(),
+		&ScanOptions{Timeout: time.Second})
+	if len(res.Resolved) != 1 {
+		t.Fatalf("got %d resolved results, want 1", len(res.Resolved))
+	}
+	assertPorts(t, res.Resolved[0], open, closed)
+	if len(res.Unresolved) != 1 || res.Unresolved[0] != unresolved {
+		t.Errorf("unresolved = %v, want [%s]", res.Unresolved, unresolved.InitialTarget)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"fmt"
+	"github.com/ElCap1tan/gort/netUtil"
+	"io"
+	"strings"
+)
+
+// GrepWriter writes scan results in a grepable format similar to the one of nmap with exactly one line per host.
+// Every line contains tab separated fields in the form of
+//
+// Host: 192.88.99.1 (example.com)	Status: Up	Ports: 22/open/tcp//ssh///, 23/closed/tcp//telnet///
+//
+// Because every ScanResult is written on its own, a GrepWriter can also be used while streaming results.
+type GrepWriter struct {
+	w io.Writer
+}
+
+// NewGrepWriter returns a pointer to a new GrepWriter writing to w.
+func NewGrepWriter(w io.Writer) *GrepWriter {
+	return &GrepWriter{w: w}
+}
+
+// Write writes the line representing the ScanResult s.
+func (g *GrepWriter) Write(s *ScanResult) error {
+	t := s.Target
+	var ports []string
+	s.Ports.each(func(p *netUtil.Port, state PortState) {
+		ports = append(ports, grepPort(p, state, s.Ports.Detail(p)))
+	})
+
+	line := fmt.Sprintf("Host: %s (%s)\tStatus: %s", t.IPAddr, grepHostName(t), grepStatus(t.Status))
+	if len(ports) > 0 {
+		line += "\tPorts: " + strings.Join(ports, ", ")
+	}
+	if t.MACAddr != nil {
+		line += "\tMAC: " + t.MACAddr.String()
+		if t.Vendor != "" && t.Vendor != "N/A" {
+			line += " (" + t.Vendor + ")"
+		}
+	}
+	_, err := io.WriteString(g.w, line+"\n")
+	return err
+}
+
+// WriteGrepable writes every resolved ScanResult of the MultiScanResult in the grepable format of GrepWriter to w.
+func (m *MultiScanResult) WriteGrepable(w io.Writer) error {
+	g := NewGrepWriter(w)
+	for _, s := range m.Resolved {
+		if err := g.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grepPort returns the grepable representation of p with the given state.
+// If a service was detected on the port, its name and version as contained in d are used.
+func grepPort(p *netUtil.Port, state PortState, d *PortDetail) string {
+	service, version := p.Service, ""
+	if service == "N/A" {
+		service = ""
+	}
+	if d != nil && d.Service != nil {
+		if d.Service.Name != "" {
+			service = d.Service.Name
+		}
+		version = d.Service.versionString()
+		if d.Service.ExtraInfo != "" {
+			version = strings.TrimSpace(version + " (" + d.Service.ExtraInfo + ")")
+		}
+	}
+	// Slashes and commas are used as separators and thus can't be part of a field.
+	r := strings.NewReplacer("/", "|", ",", "|")
+	return fmt.Sprintf("%d/%s/%s//%s//%s/", p.PortNo, state.nmapString(), p.Protocol, r.Replace(service), r.Replace(version))
+}
+
+// grepHostName returns the host name of t as used in the grepable output or an empty string if it is unknown.
+func grepHostName(t *Target) string {
+	if t.HostName == "N/A" {
+		return ""
+	}
+	return string(t.HostName)
+}
+
+// grepStatus maps ts to the host states used in the grepable output.
+func grepStatus(ts TargetStatus) string {
+	if ts == Online {
+		return "Up"
+	} else if ts == OfflineFiltered {
+		return "Down"
+	}
+	return "Unknown"
+}
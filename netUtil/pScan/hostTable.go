@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+)
+
+// HostTable returns a compact table of the resolved Targets that weren't determined to be down, with one line per
+// host containing its IP address, host name, MAC address, vendor, average ping and status, followed by the number
+// of hosts that are up.
+func (t Targets) HostTable() string {
+	return t.renderHostTable(TargetStatus.String)
+}
+
+// ColorHostTable returns a colored version of the table returned by Targets.HostTable.
+func (t Targets) ColorHostTable() string {
+	return t.renderHostTable(TargetStatus.ColorString)
+}
+
+// renderHostTable returns the table of Targets.HostTable with the status of the hosts formatted by status.
+// The status is the last column, so color codes don't break the alignment of the table.
+func (t Targets) renderHostTable(status func(ts TargetStatus) string) string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "IP\tHOSTNAME\tMAC\tVENDOR\tAVG PING\tSTATUS")
+	resolved, up := 0, 0
+	for _, target := range t {
+		if target.IPAddr == nil || target.Excluded() {
+			continue
+		}
+		resolved++
+		if target.CurrentStatus() == Online {
+			up++
+		}
+		if target.Down() {
+			continue
+		}
+		mac, rtt := "N/A", "N/A"
+		if target.MACAddr != nil {
+			mac = target.MACAddr.String()
+		}
+		if avg := target.AvgRTT(); avg > 0 {
+			rtt = avg.String()
+		}
+		vendor := target.Vendor
+		if vendor == "" {
+			vendor = "N/A"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			target.IPAddr, target.HostName, mac, vendor, rtt, status(target.CurrentStatus()))
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(&buf, "%d of %d hosts up\n", up, resolved)
+	return buf.String()
+}
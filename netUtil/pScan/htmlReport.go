@@ -0,0 +1,325 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"fmt"
+	"github.com/ElCap1tan/gort/netUtil"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// htmlReport contains the data the HTML report template is rendered with.
+type htmlReport struct {
+	Generated  string
+	StartTime  string
+	EndTime    string
+	Locations  []string
+	Summary    []htmlSummaryRow
+	Totals     []int
+	Total      int
+	Hosts      []htmlHost
+	Unresolved []string
+}
+
+// htmlSummaryRow contains the number of hosts with a given status per network location.
+type htmlSummaryRow struct {
+	Status string
+	Class  string
+	Counts []int
+	Total  int
+}
+
+// htmlHost contains the data of a single scanned host in the HTML report.
+type htmlHost struct {
+	ID          string
+	Target      string
+	IP          string
+	HostName    string
+	MAC         string
+	Vendor      string
+	Status      string
+	StatusClass string
+	Location    string
+	AvgRTT      string
+	AvgRTTNs    int64
+	StartTime   string
+	EndTime     string
+	Open        int
+	Closed      int
+	Filtered    int
+	Errored     int
+	Ports       []htmlPort
+}
+
+// htmlPort contains the data of a single scanned port in the HTML report.
+type htmlPort struct {
+	Port        uint16
+	Protocol    string
+	State       string
+	StateClass  string
+	Service     string
+	Version     string
+	TLS         string
+	TLSWarning  bool
+	Description string
+	Error       string
+}
+
+// WriteHTML writes a self-contained HTML report of the MultiScanResult to w.
+// The report contains a summary of the hosts by TargetStatus and NetworkLocation, a sortable and filterable
+// overview of all hosts and collapsible port tables for every host. All styles and scripts are embedded,
+// so the report doesn't depend on any external assets.
+func (m *MultiScanResult) WriteHTML(w io.Writer) error {
+	return m.writeHTML(w, time.Now())
+}
+
+// writeHTML works like WriteHTML but states generated as the time the report was generated at.
+func (m *MultiScanResult) writeHTML(w io.Writer, generated time.Time) error {
+	statuses := []TargetStatus{Online, OfflineFiltered, Unknown}
+	locations := []NetworkLocation{Local, Global, UnknownLoc}
+
+	report := htmlReport{Generated: generated.Format(time.RFC1123)}
+	for _, l := range locations {
+		report.Locations = append(report.Locations, l.String())
+	}
+	report.Totals = make([]int, len(locations))
+	for _, ts := range statuses {
+		row := htmlSummaryRow{Status: ts.String(), Class: ts.id(), Counts: make([]int, len(locations))}
+		for _, s := range m.Resolved {
+			if s.Target.Status != ts {
+				continue
+			}
+			for i, l := range locations {
+				if s.Target.Location == l {
+					row.Counts[i]++
+					report.Totals[i]++
+				}
+			}
+			row.Total++
+		}
+		report.Total += row.Total
+		report.Summary = append(report.Summary, row)
+	}
+
+	var start, end time.Time
+	for i, s := range m.Resolved {
+		if start.IsZero() || s.StartTime.Before(start) {
+			start = s.StartTime
+		}
+		if end.IsZero() || s.EndTime.After(end) {
+			end = s.EndTime
+		}
+		report.Hosts = append(report.Hosts, newHTMLHost(i, s))
+	}
+	if !start.IsZero() {
+		report.StartTime = start.Format(time.RFC1123)
+		report.EndTime = end.Format(time.RFC1123)
+	}
+	for _, t := range m.Unresolved {
+		report.Unresolved = append(report.Unresolved, t.InitialTarget)
+	}
+	return htmlReportTemplate.Execute(w, report)
+}
+
+// newHTMLHost converts the ScanResult s with index i to its HTML report representation.
+func newHTMLHost(i int, s *ScanResult) htmlHost {
+	t := s.Target
+	h := htmlHost{
+		ID:          fmt.Sprintf("host-%d", i),
+		Target:      t.InitialTarget,
+		IP:          t.IPAddr.String(),
+		HostName:    grepHostName(t),
+		Vendor:      t.Vendor,
+		Status:      t.Status.String(),
+		StatusClass: t.Status.id(),
+		Location:    t.Location.String(),
+		AvgRTTNs:    -1,
+		StartTime:   s.StartTime.Format(time.RFC1123),
+		EndTime:     s.EndTime.Format(time.RFC1123),
+		Open:        len(s.Ports.Open),
+		Closed:      len(s.Ports.Closed),
+		Filtered:    len(s.Ports.Filtered) + len(s.Ports.OpenFiltered),
+		Errored:     len(s.Ports.Errored),
+	}
+	if t.MACAddr != nil {
+		h.MAC = t.MACAddr.String()
+	}
+	if h.Vendor == "N/A" {
+		h.Vendor = ""
+	}
+	if avg := t.AvgRTT(); avg > 0 {
+		h.AvgRTT = avg.String()
+		h.AvgRTTNs = int64(avg)
+	}
+	s.Ports.each(func(p *netUtil.Port, state PortState) {
+		h.Ports = append(h.Ports, newHTMLPort(p, state, s.Ports.Detail(p)))
+	})
+	return h
+}
+
+// newHTMLPort converts p with the given state and the additional information in d, which may be nil,
+// to its HTML report representation.
+func newHTMLPort(p *netUtil.Port, state PortState, d *PortDetail) htmlPort {
+	hp := htmlPort{Port: p.PortNo, Protocol: p.Protocol, State: state.String(), StateClass: state.id()}
+	if p.Service != "N/A" {
+		hp.Service = p.Service
+		hp.Description = strings.Replace(p.Description, "\n", " ", -1)
+	}
+	if d != nil && d.Service != nil {
+		if d.Service.Name != "" {
+			hp.Service = d.Service.Name
+		}
+		hp.Version = d.Service.versionString()
+		if d.Service.ExtraInfo != "" {
+			hp.Version = strings.TrimSpace(hp.Version + " (" + d.Service.ExtraInfo + ")")
+		}
+	}
+	if d != nil && d.TLS != nil {
+		hp.TLS = d.TLS.String()
+		hp.TLSWarning = len(d.TLS.flags()) > 0
+	}
+	if d != nil && d.Err != nil {
+		hp.Error = d.Err.Error()
+	}
+	return hp
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>gort scan report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; background: #fafafa; }
+h1, h2 { font-weight: 400; }
+table { border-collapse: collapse; margin: .5em 0 1.5em 0; background: #fff; }
+th, td { border: 1px solid #ddd; padding: .3em .7em; text-align: left; }
+th { background: #eee; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th.asc::after { content: " \25B2"; }
+table.sortable th.desc::after { content: " \25BC"; }
+details { margin: .5em 0; background: #fff; border: 1px solid #ddd; padding: .5em 1em; }
+summary { cursor: pointer; }
+.online { color: #1a7f37; }
+.offline-filtered { color: #cf222e; }
+.unknown { color: #9a6700; }
+.open { color: #1a7f37; }
+.closed { color: #cf222e; }
+.tls { white-space: pre-line; }
+.filtered, .open-filtered, .error { color: #9a6700; }
+.controls { margin: 1em 0; }
+.controls input[type=text] { padding: .3em; width: 25em; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>gort scan report</h1>
+<p>Report generated @ {{.Generated}}{{if .StartTime}}<br>Scan started @ {{.StartTime}}<br>Scan finished @ {{.EndTime}}{{end}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Status</th>{{range .Locations}}<th>{{.}}</th>{{end}}<th>Total</th></tr>
+{{range .Summary}}<tr><td class="{{.Class}}">{{.Status}}</td>{{range .Counts}}<td>{{.}}</td>{{end}}<td>{{.Total}}</td></tr>
+{{end}}<tr><th>Total</th>{{range .Totals}}<th>{{.}}</th>{{end}}<th>{{.Total}}</th></tr>
+</table>
+{{if .Unresolved}}<p>Unresolved targets: {{range $i, $t := .Unresolved}}{{if $i}}, {{end}}{{$t}}{{end}}</p>{{end}}
+
+<h2>Hosts</h2>
+<div class="controls">
+<input type="text" id="filter" placeholder="Filter hosts and ports...">
+<label><input type="checkbox" id="online-only"> Only online hosts</label>
+<label><input type="checkbox" id="show-closed" checked> Show closed and filtered ports</label>
+</div>
+<table class="sortable" id="hosts">
+<thead><tr><th>Target</th><th>IP</th><th>Hostname</th><th>Status</th><th>Location</th><th>MAC</th><th>Vendor</th><th>Avg RTT</th><th>Open</th><th>Closed</th><th>Filtered</th><th>Errored</th></tr></thead>
+<tbody>
+{{range .Hosts}}<tr data-host="{{.ID}}" data-status="{{.StatusClass}}"><td><a href="#{{.ID}}">{{.Target}}</a></td><td>{{.IP}}</td><td>{{.HostName}}</td><td class="{{.StatusClass}}">{{.Status}}</td><td>{{.Location}}</td><td>{{.MAC}}</td><td>{{.Vendor}}</td><td data-sort="{{.AvgRTTNs}}">{{.AvgRTT}}</td><td>{{.Open}}</td><td>{{.Closed}}</td><td>{{.Filtered}}</td><td>{{.Errored}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Ports</h2>
+{{range .Hosts}}<details class="host" id="{{.ID}}" data-status="{{.StatusClass}}">
+<summary><b>{{.Target}}</b> ({{.IP}}{{if .HostName}} / {{.HostName}}{{end}}) - <span class="{{.StatusClass}}">{{.Status}}</span> - {{.Open}} open</summary>
+<p>Scan started @ {{.StartTime}}<br>Scan finished @ {{.EndTime}}</p>
+<table class="sortable ports">
+<thead><tr><th>Port</th><th>Protocol</th><th>State</th><th>Service</th><th>Version</th><th>TLS</th><th>Description</th></tr></thead>
+<tbody>
+{{range .Ports}}<tr data-state="{{.StateClass}}"><td>{{.Port}}</td><td>{{.Protocol}}</td><td class="{{.StateClass}}"{{if .Error}} title="{{.Error}}"{{end}}>{{.State}}</td><td>{{.Service}}</td><td>{{.Version}}</td><td class="tls{{if .TLSWarning}} closed{{end}}">{{.TLS}}</td><td>{{.Description}}</td></tr>
+{{end}}</tbody>
+</table>
+</details>
+{{end}}
+<script>
+(function () {
+	function cellValue(row, i) {
+		var cell = row.cells[i];
+		return cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent.trim();
+	}
+	document.querySelectorAll("table.sortable").forEach(function (table) {
+		table.querySelectorAll("th").forEach(function (th, i) {
+			th.addEventListener("click", function () {
+				var asc = !th.classList.contains("asc");
+				table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+				th.classList.add(asc ? "asc" : "desc");
+				var body = table.tBodies[0];
+				var rows = Array.prototype.slice.call(body.rows);
+				rows.sort(function (a, b) {
+					var x = cellValue(a, i), y = cellValue(b, i);
+					var nx = parseFloat(x), ny = parseFloat(y);
+					var cmp = !isNaN(nx) && !isNaN(ny) ? nx - ny : x.localeCompare(y, undefined, {numeric: true});
+					return asc ? cmp : -cmp;
+				});
+				rows.forEach(function (r) { body.appendChild(r); });
+			});
+		});
+	});
+
+	var filter = document.getElementById("filter");
+	var onlineOnly = document.getElementById("online-only");
+	var showClosed = document.getElementById("show-closed");
+	function applyFilter() {
+		var q = filter.value.toLowerCase();
+		document.querySelectorAll("#hosts tbody tr").forEach(function (row) {
+			var details = document.getElementById(row.getAttribute("data-host"));
+			var statusOk = !onlineOnly.checked || row.getAttribute("data-status") === "online";
+			var match = statusOk && (q === "" || row.textContent.toLowerCase().indexOf(q) >= 0 ||
+				details.textContent.toLowerCase().indexOf(q) >= 0);
+			row.classList.toggle("hidden", !match);
+			details.classList.toggle("hidden", !match);
+		});
+		document.querySelectorAll("table.ports tbody tr").forEach(function (row) {
+			var stateOk = showClosed.checked || row.getAttribute("data-state") === "open";
+			var hostMatch = q === "" || row.closest("details").querySelector("summary").textContent.toLowerCase().indexOf(q) >= 0;
+			var match = stateOk && (hostMatch || row.textContent.toLowerCase().indexOf(q) >= 0);
+			row.classList.toggle("hidden", !match);
+		});
+	}
+	filter.addEventListener("input", applyFilter);
+	onlineOnly.addEventListener("change", applyFilter);
+	showClosed.addEventListener("change", applyFilter);
+})();
+</script>
+</body>
+</html>
+`))
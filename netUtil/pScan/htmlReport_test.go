@@ -0,0 +1,62 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML(t *testing.T) {
+	m := newTestMultiScanResult()
+	var buf bytes.Buffer
+	if err := m.writeHTML(&buf, time.Date(2020, time.October, 11, 13, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "scan.html", buf.Bytes())
+}
+
+func TestWriteHTMLIsSelfContained(t *testing.T) {
+	m := newTestMultiScanResult()
+	var buf bytes.Buffer
+	if err := m.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, external := range []string{"<link", "src=\"http", "src='http", "@import"} {
+		if strings.Contains(buf.String(), external) {
+			t.Errorf("report references external assets: found %q", external)
+		}
+	}
+}
+
+func TestWriteHTMLEscapes(t *testing.T) {
+	m := newTestMultiScanResult()
+	m.Unresolved[0].InitialTarget = "<script>alert(1)</script>"
+	var buf bytes.Buffer
+	if err := m.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<script>alert(1)</script>") {
+		t.Error("target names aren't escaped")
+	}
+}
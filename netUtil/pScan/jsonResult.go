@@ -0,0 +1,237 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/json"
+	"github.com/ElCap1tan/gort/netUtil"
+	"io"
+	"time"
+)
+
+// JSONSchemaVersion is the version of the JSON document produced by MultiScanResult.MarshalJSON.
+// It is only incremented on incompatible changes. New fields may be added without changing the version.
+const JSONSchemaVersion = 1
+
+// jsonScan is the root element of the JSON representation of a MultiScanResult.
+type jsonScan struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Scanner       string       `json:"scanner"`
+	StartTime     *time.Time   `json:"startTime,omitempty"`
+	EndTime       *time.Time   `json:"endTime,omitempty"`
+	Targets       []string     `json:"targets"`
+	Resolved      []jsonHost   `json:"resolved"`
+	Unresolved    []jsonTarget `json:"unresolved"`
+}
+
+// jsonTarget is the JSON representation of a Target.
+type jsonTarget struct {
+	Target   string    `json:"target"`
+	IP       string    `json:"ip,omitempty"`
+	HostName string    `json:"hostname,omitempty"`
+	MAC      string    `json:"mac,omitempty"`
+	Vendor   string    `json:"vendor,omitempty"`
+	Status   string    `json:"status"`
+	Location string    `json:"location"`
+	RTTs     []float64 `json:"rttsMs"`
+	AvgRTT   float64   `json:"avgRttMs,omitempty"`
+}
+
+// jsonHost is the JSON representation of the ScanResult of a resolved Target.
+type jsonHost struct {
+	jsonTarget
+	StartTime time.Time  `json:"startTime"`
+	EndTime   time.Time  `json:"endTime"`
+	Ports     []jsonPort `json:"ports"`
+}
+
+// jsonPort is the JSON representation of a single scanned port.
+type jsonPort struct {
+	Port        uint16       `json:"port"`
+	Protocol    string       `json:"protocol"`
+	State       string       `json:"state"`
+	Reason      string       `json:"reason,omitempty"`
+	Service     string       `json:"service,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Detected    *jsonService `json:"detected,omitempty"`
+	TLS         *jsonTLS     `json:"tls,omitempty"`
+	Attempts    int          `json:"attempts,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// jsonService is the JSON representation of a ServiceInfo.
+type jsonService struct {
+	Name      string `json:"name,omitempty"`
+	Product   string `json:"product,omitempty"`
+	Version   string `json:"version,omitempty"`
+	ExtraInfo string `json:"extraInfo,omitempty"`
+	Banner    string `json:"banner,omitempty"`
+	Probe     string `json:"probe"`
+}
+
+// jsonTLS is the JSON representation of a TLSInfo.
+type jsonTLS struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipherSuite"`
+	Subject     string    `json:"subject"`
+	SANs        []string  `json:"sans"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	KeyType     string    `json:"keyType"`
+	KeyBits     int       `json:"keyBits,omitempty"`
+	SelfSigned  bool      `json:"selfSigned"`
+	Trusted     bool      `json:"trusted"`
+	Expired     bool      `json:"expired"`
+	ExpiresSoon bool      `json:"expiresSoon"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The document layout is versioned by JSONSchemaVersion.
+func (m MultiScanResult) MarshalJSON() ([]byte, error) {
+	doc := jsonScan{
+		SchemaVersion: JSONSchemaVersion,
+		Scanner:       "gort",
+		Targets:       []string{},
+		Resolved:      []jsonHost{},
+		Unresolved:    []jsonTarget{},
+	}
+	for _, s := range m.Resolved {
+		if doc.StartTime == nil || s.StartTime.Before(*doc.StartTime) {
+			startTime := s.StartTime
+			doc.StartTime = &startTime
+		}
+		if doc.EndTime == nil || s.EndTime.After(*doc.EndTime) {
+			endTime := s.EndTime
+			doc.EndTime = &endTime
+		}
+		doc.Targets = append(doc.Targets, s.Target.InitialTarget)
+		doc.Resolved = append(doc.Resolved, newJSONHost(s))
+	}
+	for _, t := range m.Unresolved {
+		doc.Targets = append(doc.Targets, t.InitialTarget)
+		doc.Unresolved = append(doc.Unresolved, newJSONTarget(t))
+	}
+	return json.Marshal(doc)
+}
+
+// WriteJSON writes the indented JSON representation of the MultiScanResult to w.
+func (m *MultiScanResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// newJSONTarget converts t to its JSON representation.
+func newJSONTarget(t *Target) jsonTarget {
+	jt := jsonTarget{
+		Target:   t.InitialTarget,
+		Vendor:   t.Vendor,
+		Status:   t.Status.id(),
+		Location: t.Location.id(),
+		RTTs:     []float64{},
+	}
+	if t.IPAddr != nil {
+		jt.IP = t.IPAddr.String()
+	}
+	if t.HostName != "N/A" {
+		jt.HostName = string(t.HostName)
+	}
+	if t.MACAddr != nil {
+		jt.MAC = t.MACAddr.String()
+	}
+	if jt.Vendor == "N/A" {
+		jt.Vendor = ""
+	}
+	for _, rtt := range t.RTTs {
+		jt.RTTs = append(jt.RTTs, durationToMs(rtt))
+	}
+	if avg := t.AvgRTT(); avg > 0 {
+		jt.AvgRTT = durationToMs(avg)
+	}
+	return jt
+}
+
+// newJSONHost converts s to its JSON representation.
+func newJSONHost(s *ScanResult) jsonHost {
+	jh := jsonHost{
+		jsonTarget: newJSONTarget(s.Target),
+		StartTime:  s.StartTime,
+		EndTime:    s.EndTime,
+		Ports:      []jsonPort{},
+	}
+	s.Ports.each(func(p *netUtil.Port, state PortState) {
+		jh.Ports = append(jh.Ports, newJSONPort(p, state, s.Ports.Detail(p)))
+	})
+	return jh
+}
+
+// newJSONPort converts p with the given state and the additional information in d, which may be nil,
+// to its JSON representation.
+func newJSONPort(p *netUtil.Port, state PortState, d *PortDetail) jsonPort {
+	jp := jsonPort{Port: p.PortNo, Protocol: p.Protocol, State: state.String()}
+	if p.Service != "N/A" {
+		jp.Service = p.Service
+		jp.Description = p.Description
+	}
+	if d != nil && d.Service != nil {
+		jp.Detected = &jsonService{
+			Name:      d.Service.Name,
+			Product:   d.Service.Product,
+			Version:   d.Service.Version,
+			ExtraInfo: d.Service.ExtraInfo,
+			Banner:    d.Service.Banner,
+			Probe:     d.Service.Probe,
+		}
+	}
+	if d != nil && d.TLS != nil {
+		jp.TLS = &jsonTLS{
+			Version:     d.TLS.Version,
+			CipherSuite: d.TLS.CipherSuite,
+			Subject:     d.TLS.Subject,
+			SANs:        append([]string{}, d.TLS.SANs...),
+			Issuer:      d.TLS.Issuer,
+			NotBefore:   d.TLS.NotBefore,
+			NotAfter:    d.TLS.NotAfter,
+			KeyType:     d.TLS.KeyType,
+			KeyBits:     d.TLS.KeyBits,
+			SelfSigned:  d.TLS.SelfSigned,
+			Trusted:     d.TLS.Trusted,
+			Expired:     d.TLS.Expired,
+			ExpiresSoon: d.TLS.ExpiresSoon,
+		}
+	}
+	if d != nil {
+		if d.Reason != ReasonUnknown {
+			jp.Reason = d.Reason.String()
+		}
+		jp.Attempts = d.Attempts
+		if d.Err != nil {
+			jp.Error = d.Err.Error()
+		}
+	}
+	return jp
+}
+
+// durationToMs converts d to fractional milliseconds.
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	m := newTestMultiScanResult()
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "scan.json", buf.Bytes())
+}
+
+func TestMarshalJSONEmpty(t *testing.T) {
+	data, err := json.Marshal(MultiScanResult{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := doc["schemaVersion"].(float64); !ok || int(v) != JSONSchemaVersion {
+		t.Errorf("schemaVersion = %v, want %d", doc["schemaVersion"], JSONSchemaVersion)
+	}
+	for _, key := range []string{"targets", "resolved", "unresolved"} {
+		if list, ok := doc[key].([]interface{}); !ok || len(list) != 0 {
+			t.Errorf("%s = %v, want an empty list", key, doc[key])
+		}
+	}
+	for _, key := range []string{"startTime", "endTime"} {
+		if _, ok := doc[key]; ok {
+			t.Errorf("%s is set for an empty scan", key)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// ndpResolve sends a NDP neighbor solicitation for ip over the interface inf and returns the MAC address
+// contained in the neighbor advertisement of the target. This is the IPv6 counterpart to an ARP request and
+// requires root privileges. The request is aborted once timeout is reached or ctx is done.
+func ndpResolve(ctx context.Context, inf *net.Interface, ip net.IP, timeout time.Duration) (net.HardwareAddr, error) {
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	pc := conn.IPv6PacketConn()
+	// Neighbor discovery messages are only accepted if they weren't forwarded by a router.
+	if err = pc.SetMulticastHopLimit(255); err != nil {
+		return nil, err
+	}
+	if err = pc.SetHopLimit(255); err != nil {
+		return nil, err
+	}
+	if err = pc.SetMulticastInterface(inf); err != nil {
+		return nil, err
+	}
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeNeighborAdvertisement)
+	_ = pc.SetICMPFilter(&filter)
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Unblocks the pending read
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	// Reserved field, target address and the source link-layer address option
+	body := make([]byte, 4, 4+16+8)
+	body = append(body, ip.To16()...)
+	body = append(body, 1, 1)
+	body = append(body, inf.HardwareAddr...)
+	msg := icmp.Message{Type: ipv6.ICMPTypeNeighborSolicitation, Body: &icmp.RawBody{Data: body}}
+	// The checksum is calculated by the kernel.
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.WriteTo(b, &net.IPAddr{IP: solicitedNodeAddr(ip), Zone: inf.Name}); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, err
+		}
+		if hwAddr := parseNeighborAdvertisement(buf[:n], ip); hwAddr != nil {
+			return hwAddr, nil
+		}
+	}
+}
+
+// parseNeighborAdvertisement returns the target link-layer address of the ICMPv6 message in b
+// if it is a neighbor advertisement for ip and nil otherwise.
+func parseNeighborAdvertisement(b []byte, ip net.IP) net.HardwareAddr {
+	// Type, code, checksum, flags and the target address
+	if len(b) < 24 || b[0] != byte(ipv6.ICMPTypeNeighborAdvertisement) || !bytes.Equal(b[8:24], ip.To16()) {
+		return nil
+	}
+	opts := b[24:]
+	for len(opts) >= 8 {
+		l := int(opts[1]) * 8
+		if l == 0 || l > len(opts) {
+			return nil
+		}
+		// Target link-layer address option
+		if opts[0] == 2 && l >= 8 {
+			return net.HardwareAddr(append([]byte(nil), opts[2:8]...))
+		}
+		opts = opts[l:]
+	}
+	return nil
+}
+
+// solicitedNodeAddr returns the solicited-node multicast address of ip.
+func solicitedNodeAddr(ip net.IP) net.IP {
+	addr := net.ParseIP("ff02::1:ff00:0")
+	copy(addr[13:], ip.To16()[13:])
+	return addr
+}
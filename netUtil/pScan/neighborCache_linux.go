@@ -0,0 +1,50 @@
+// +build linux
+
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// searchNeighborCache returns the MAC address of ip found in the IPv6 neighbor cache of the system
+// or nil if the cache contains no entry for ip.
+func searchNeighborCache(ip net.IP) net.HardwareAddr {
+	out, err := exec.Command("ip", "-6", "neigh", "show", ip.String()).Output()
+	if err != nil {
+		return nil
+	}
+	// fe80::1 dev eth0 lladdr 00:11:22:33:44:55 router REACHABLE
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields)-1; i++ {
+			if fields[i] == "lladdr" {
+				if hwAddr, err := net.ParseMAC(fields[i+1]); err == nil {
+					return hwAddr
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,419 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/ElCap1tan/gort/netUtil"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmapXMLOutputVersion is the version of the nmap XML output format the document produced by
+// MultiScanResult.WriteNmapXML is compatible with.
+const nmapXMLOutputVersion = "1.05"
+
+// ScannerVersion is the gort version reported in the version attribute of nmap XML documents. Builds that aren't
+// releases report "dev". Releases set it at build time using
+// -ldflags "-X github.com/ElCap1tan/gort/netUtil/pScan.ScannerVersion=<version>".
+var ScannerVersion = "dev"
+
+// nmapRun is the root element of an nmap XML document.
+type nmapRun struct {
+	XMLName          xml.Name       `xml:"nmaprun"`
+	Scanner          string         `xml:"scanner,attr"`
+	Args             string         `xml:"args,attr"`
+	Version          string         `xml:"version,attr"`
+	Start            int64          `xml:"start,attr"`
+	StartStr         string         `xml:"startstr,attr"`
+	XMLOutputVersion string         `xml:"xmloutputversion,attr"`
+	ScanInfo         []nmapScanInfo `xml:"scaninfo"`
+	Verbose          nmapLevel      `xml:"verbose"`
+	Debugging        nmapLevel      `xml:"debugging"`
+	Hosts            []nmapHost     `xml:"host"`
+	RunStats         nmapRunStats   `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapLevel struct {
+	Level int `xml:"level,attr"`
+}
+
+type nmapHost struct {
+	StartTime int64          `xml:"starttime,attr"`
+	EndTime   int64          `xml:"endtime,attr"`
+	Status    nmapStatus     `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	HostNames []nmapHostName `xml:"hostnames>hostname"`
+	Ports     []nmapPort     `xml:"ports>port"`
+	Times     *nmapTimes     `xml:"times,omitempty"`
+}
+
+type nmapStatus struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr,omitempty"`
+}
+
+type nmapHostName struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   uint16       `xml:"portid,attr"`
+	State    nmapState    `xml:"state"`
+	Service  *nmapService `xml:"service,omitempty"`
+	Scripts  []nmapScript `xml:"script"`
+}
+
+type nmapScript struct {
+	ID     string      `xml:"id,attr"`
+	Output string      `xml:"output,attr"`
+	Elems  []nmapElem  `xml:"elem"`
+	Tables []nmapTable `xml:"table"`
+}
+
+type nmapTable struct {
+	Key   string     `xml:"key,attr"`
+	Elems []nmapElem `xml:"elem"`
+}
+
+type nmapElem struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type nmapState struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapService struct {
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr,omitempty"`
+	Version   string `xml:"version,attr,omitempty"`
+	ExtraInfo string `xml:"extrainfo,attr,omitempty"`
+	Method    string `xml:"method,attr"`
+	Conf      int    `xml:"conf,attr"`
+}
+
+type nmapTimes struct {
+	SRTT   int64 `xml:"srtt,attr"`
+	RTTVar int64 `xml:"rttvar,attr"`
+	To     int64 `xml:"to,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64  `xml:"time,attr"`
+	TimeStr string `xml:"timestr,attr"`
+	Elapsed string `xml:"elapsed,attr"`
+	Summary string `xml:"summary,attr"`
+	Exit    string `xml:"exit,attr"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// WriteNmapXML writes the MultiScanResult to w as an XML document compatible with the XML output of nmap
+// (https://nmap.org/book/nmap-dtd.html), so that it can be processed by existing nmap tooling.
+// Unresolved targets have no address and thus are not part of the document. The Args of the MultiScanResult are
+// reported as the command line of the scan.
+func (m *MultiScanResult) WriteNmapXML(w io.Writer) error {
+	var start, end time.Time
+	for _, s := range m.Resolved {
+		if start.IsZero() || s.StartTime.Before(start) {
+			start = s.StartTime
+		}
+		if end.IsZero() || s.EndTime.After(end) {
+			end = s.EndTime
+		}
+	}
+	if start.IsZero() {
+		start, end = time.Now(), time.Now()
+	}
+
+	run := nmapRun{
+		Scanner:          "gort",
+		Args:             strings.Join(m.Args, " "),
+		Version:          ScannerVersion,
+		Start:            start.Unix(),
+		StartStr:         start.Format(time.ANSIC),
+		XMLOutputVersion: nmapXMLOutputVersion,
+	}
+
+	services := make(map[string]map[uint16]bool)
+	tcpType := "connect"
+	for _, s := range m.Resolved {
+		if s.Technique == SynScan {
+			tcpType = "syn"
+		}
+		h := newNmapHost(s)
+		if h.Status.State == "up" {
+			run.RunStats.Hosts.Up++
+		} else {
+			run.RunStats.Hosts.Down++
+		}
+		for _, p := range h.Ports {
+			if services[p.Protocol] == nil {
+				services[p.Protocol] = make(map[uint16]bool)
+			}
+			services[p.Protocol][p.PortID] = true
+		}
+		run.Hosts = append(run.Hosts, h)
+	}
+	run.RunStats.Hosts.Total = len(m.Resolved)
+
+	for _, proto := range portProtocols {
+		if ports, ok := services[proto]; ok {
+			info := nmapScanInfo{Type: tcpType, Protocol: proto, NumServices: len(ports), Services: compressPortNumbers(ports)}
+			if proto == "udp" {
+				info.Type = "udp"
+			}
+			run.ScanInfo = append(run.ScanInfo, info)
+		}
+	}
+
+	elapsed := end.Sub(start).Seconds()
+	run.RunStats.Finished = nmapFinished{
+		Time:    end.Unix(),
+		TimeStr: end.Format(time.ANSIC),
+		Elapsed: fmt.Sprintf("%.2f", elapsed),
+		Summary: fmt.Sprintf("gort done at %s; %d IP addresses (%d hosts up) scanned in %.2f seconds",
+			end.Format(time.ANSIC), run.RunStats.Hosts.Total, run.RunStats.Hosts.Up, elapsed),
+		Exit: "success",
+	}
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// newNmapHost converts the ScanResult s to an nmap host element.
+func newNmapHost(s *ScanResult) nmapHost {
+	t := s.Target
+	h := nmapHost{
+		StartTime: s.StartTime.Unix(),
+		EndTime:   s.EndTime.Unix(),
+		Status:    nmapStatus{State: nmapHostState(t.Status), Reason: nmapHostReason(s)},
+	}
+
+	addrType := "ipv4"
+	if t.IPAddr.To4() == nil {
+		addrType = "ipv6"
+	}
+	h.Addresses = append(h.Addresses, nmapAddress{Addr: t.IPAddr.String(), AddrType: addrType})
+	if t.MACAddr != nil {
+		mac := nmapAddress{Addr: t.MACAddr.String(), AddrType: "mac"}
+		if t.Vendor != "N/A" {
+			mac.Vendor = t.Vendor
+		}
+		h.Addresses = append(h.Addresses, mac)
+	}
+
+	if t.InitialTarget != t.IPAddr.String() {
+		h.HostNames = append(h.HostNames, nmapHostName{Name: t.InitialTarget, Type: "user"})
+	}
+	if t.HostName != "" && t.HostName != "N/A" && string(t.HostName) != t.InitialTarget {
+		h.HostNames = append(h.HostNames, nmapHostName{Name: string(t.HostName), Type: "PTR"})
+	}
+
+	s.Ports.each(func(p *netUtil.Port, state PortState) {
+		h.Ports = append(h.Ports, newNmapPort(p, state, s.Ports.Detail(p)))
+	})
+
+	if len(t.RTTs) > 0 {
+		srtt := t.AvgRTT()
+		var variance time.Duration
+		for _, rtt := range t.RTTs {
+			if rtt > srtt {
+				variance += rtt - srtt
+			} else {
+				variance += srtt - rtt
+			}
+		}
+		variance /= time.Duration(len(t.RTTs))
+		h.Times = &nmapTimes{
+			SRTT:   srtt.Microseconds(),
+			RTTVar: variance.Microseconds(),
+			To:     (srtt + 4*variance).Microseconds(),
+		}
+	}
+	return h
+}
+
+// newNmapPort converts p with the given state and the additional information in d, which may be nil,
+// to an nmap port element.
+func newNmapPort(p *netUtil.Port, state PortState, d *PortDetail) nmapPort {
+	np := nmapPort{Protocol: p.Protocol, PortID: p.PortNo, State: nmapState{State: state.nmapString(), Reason: nmapPortReason(p, state, d)}}
+	if p.Service != "" && p.Service != "N/A" {
+		np.Service = &nmapService{Name: p.Service, Method: "table", Conf: 3}
+	}
+	if d != nil && d.Service != nil && d.Service.Name != "" {
+		np.Service = &nmapService{
+			Name:      d.Service.Name,
+			Product:   d.Service.Product,
+			Version:   d.Service.Version,
+			ExtraInfo: d.Service.ExtraInfo,
+			Method:    "probed",
+			Conf:      10,
+		}
+	}
+	if d != nil && d.TLS != nil {
+		np.Scripts = append(np.Scripts, newNmapSSLCertScript(d.TLS))
+	}
+	return np
+}
+
+// newNmapSSLCertScript converts ti to the output of the nmap ssl-cert script.
+func newNmapSSLCertScript(ti *TLSInfo) nmapScript {
+	const timeLayout = "2006-01-02T15:04:05"
+	return nmapScript{
+		ID:     "ssl-cert",
+		Output: ti.String(),
+		Elems: []nmapElem{
+			{Key: "subject", Value: ti.Subject},
+			{Key: "issuer", Value: ti.Issuer},
+			{Key: "sans", Value: strings.Join(ti.SANs, ", ")},
+			{Key: "version", Value: ti.Version},
+			{Key: "cipher", Value: ti.CipherSuite},
+			{Key: "flags", Value: strings.Join(ti.flags(), ", ")},
+		},
+		Tables: []nmapTable{
+			{Key: "pubkey", Elems: []nmapElem{
+				{Key: "type", Value: strings.ToLower(ti.KeyType)},
+				{Key: "bits", Value: strconv.Itoa(ti.KeyBits)},
+			}},
+			{Key: "validity", Elems: []nmapElem{
+				{Key: "notBefore", Value: ti.NotBefore.UTC().Format(timeLayout)},
+				{Key: "notAfter", Value: ti.NotAfter.UTC().Format(timeLayout)},
+			}},
+		},
+	}
+}
+
+// nmapPortReason returns the nmap reason for the given state of p. The PortReason recorded in d is used if it
+// is known, otherwise the reason is derived from state.
+func nmapPortReason(p *netUtil.Port, state PortState, d *PortDetail) string {
+	if d != nil && d.Reason != ReasonUnknown {
+		return d.Reason.String()
+	}
+	switch state {
+	case PortOpen:
+		if p.Protocol == "udp" {
+			return "udp-response"
+		}
+		return "syn-ack"
+	case PortClosed:
+		if p.Protocol == "udp" {
+			return "port-unreach"
+		}
+		return "conn-refused"
+	case PortError:
+		return "error"
+	}
+	return "no-response"
+}
+
+// nmapHostState maps ts to the host state values used by nmap.
+func nmapHostState(ts TargetStatus) string {
+	if ts == Online {
+		return "up"
+	} else if ts == OfflineFiltered {
+		return "down"
+	}
+	return "unknown"
+}
+
+// nmapHostReason returns the nmap reason for the host state of the scanned target.
+func nmapHostReason(s *ScanResult) string {
+	t := s.Target
+	if len(t.RTTs) > 0 {
+		return "echo-reply"
+	} else if len(s.Ports.Open) > 0 {
+		return "syn-ack"
+	} else if len(s.Ports.Closed) > 0 {
+		return "conn-refused"
+	} else if t.MACAddr != nil && t.Location == Local {
+		return "arp-response"
+	}
+	return "no-response"
+}
+
+// compressPortNumbers returns a comma separated list of the port numbers in ports in ascending order
+// where consecutive numbers are joined into ranges, e.g. "21-23,80,443".
+func compressPortNumbers(ports map[uint16]bool) string {
+	var nums []int
+	for n := range ports {
+		nums = append(nums, int(n))
+	}
+	sort.Ints(nums)
+	ret := ""
+	for i := 0; i < len(nums); i++ {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if ret != "" {
+			ret += ","
+		}
+		if i == j {
+			ret += strconv.Itoa(nums[i])
+		} else {
+			ret += strconv.Itoa(nums[i]) + "-" + strconv.Itoa(nums[j])
+		}
+		i = j
+	}
+	return ret
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWriteNmapXML(t *testing.T) {
+	m := newTestMultiScanResult()
+	m.Args = []string{"gort", "-oX", "scan.xml", "web.example.com,192.0.2.1,unknown.invalid"}
+	var buf bytes.Buffer
+	if err := m.WriteNmapXML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "scan.xml", buf.Bytes())
+}
+
+func TestCompressPortNumbers(t *testing.T) {
+	tests := []struct {
+		ports []uint16
+		want  string
+	}{
+		{nil, ""},
+		{[]uint16{80}, "80"},
+		{[]uint16{80, 22, 21, 23, 443, 8080, 8081}, "21-23,80,443,8080-8081"},
+	}
+	for _, tt := range tests {
+		set := make(map[uint16]bool)
+		for _, p := range tt.ports {
+			set[p] = true
+		}
+		if got := compressPortNumbers(set); got != tt.want {
+			t.Errorf("compressPortNumbers(%v) = %q, want %q", tt.ports, got, tt.want)
+		}
+	}
+}
+
+func TestWriteNmapXMLScanType(t *testing.T) {
+	m := newTestMultiScanResult()
+	for _, technique := range []ScanTechnique{ConnectScan, SynScan} {
+		m.Resolved[0].Technique = technique
+		var buf bytes.Buffer
+		if err := m.WriteNmapXML(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var run nmapRun
+		if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+			t.Fatal(err)
+		}
+		want := "connect"
+		if technique == SynScan {
+			want = "syn"
+		}
+		if run.ScanInfo[0].Type != want {
+			t.Errorf("scaninfo type = %q, want %q", run.ScanInfo[0].Type, want)
+		}
+	}
+}
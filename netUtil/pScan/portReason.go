@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// PortReason is an integer representing the reason a port was determined to be in its PortState.
+// The names of the reasons returned by String follow the reasons reported by nmap.
+type PortReason int
+
+const (
+	// ReasonUnknown is the reason of ports whose state wasn't determined by a probe.
+	ReasonUnknown PortReason = iota
+
+	// ReasonSynAck is the reason of open TCP ports that answered the probe with a SYN/ACK packet or accepted
+	// the connection.
+	ReasonSynAck
+
+	// ReasonReset is the reason of closed TCP ports that answered a SYN probe with a RST packet.
+	ReasonReset
+
+	// ReasonRefused is the reason of closed TCP ports that refused the connection.
+	ReasonRefused
+
+	// ReasonUDPResponse is the reason of open UDP ports that answered the probe.
+	ReasonUDPResponse
+
+	// ReasonPortUnreachable is the reason of closed UDP ports that answered with an ICMP port unreachable message.
+	ReasonPortUnreachable
+
+	// ReasonNoResponse is the reason of ports that didn't answer the probe in time.
+	ReasonNoResponse
+
+	// ReasonHostUnreachable is the reason of ports whose host was reported as unreachable.
+	ReasonHostUnreachable
+
+	// ReasonNetUnreachable is the reason of ports whose network was reported as unreachable.
+	ReasonNetUnreachable
+
+	// ReasonProtoUnreachable is the reason of ports whose host reported the transport protocol as unreachable.
+	ReasonProtoUnreachable
+
+	// ReasonAdminProhibited is the reason of ports the communication with was prohibited by a firewall.
+	ReasonAdminProhibited
+
+	// ReasonConnReset is the reason of ports whose connection was reset while it was established.
+	ReasonConnReset
+
+	// ReasonResourceExhausted is the reason of ports that couldn't be probed because the local system ran out of
+	// file descriptors, buffer space or source ports.
+	ReasonResourceExhausted
+
+	// ReasonCanceled is the reason of ports that couldn't be probed because the scan was canceled or
+	// its deadline was exceeded.
+	ReasonCanceled
+
+	// ReasonError is the reason of ports whose probe failed with an unknown error.
+	ReasonError
+)
+
+// portReasonNames contains the names of the PortReason values as returned by String.
+var portReasonNames = map[PortReason]string{
+	ReasonUnknown:           "unknown",
+	ReasonSynAck:            "syn-ack",
+	ReasonReset:             "reset",
+	ReasonRefused:           "conn-refused",
+	ReasonUDPResponse:       "udp-response",
+	ReasonPortUnreachable:   "port-unreach",
+	ReasonNoResponse:        "no-response",
+	ReasonHostUnreachable:   "host-unreach",
+	ReasonNetUnreachable:    "net-unreach",
+	ReasonProtoUnreachable:  "proto-unreach",
+	ReasonAdminProhibited:   "admin-prohibited",
+	ReasonConnReset:         "conn-reset",
+	ReasonResourceExhausted: "resource-exhausted",
+	ReasonCanceled:          "canceled",
+	ReasonError:             "error",
+}
+
+// String returns the string representation of the PortReason.
+func (pr PortReason) String() string {
+	if name, ok := portReasonNames[pr]; ok {
+		return name
+	}
+	return "N/A"
+}
+
+// State returns the PortState a port of the given transport protocol is in, if it was probed with the PortReason
+// as outcome. Reasons that don't allow a statement about the port result in PortError.
+func (pr PortReason) State(protocol string) PortState {
+	switch pr {
+	case ReasonSynAck, ReasonUDPResponse:
+		return PortOpen
+	case ReasonReset, ReasonRefused, ReasonPortUnreachable:
+		return PortClosed
+	case ReasonNoResponse:
+		if protocol == "udp" {
+			return PortOpenFiltered
+		}
+		return PortFiltered
+	case ReasonHostUnreachable, ReasonNetUnreachable, ReasonProtoUnreachable, ReasonAdminProhibited:
+		return PortFiltered
+	}
+	return PortError
+}
+
+// transient returns true if a probe with the PortReason as outcome is worth repeating.
+func (pr PortReason) transient() bool {
+	return pr == ReasonNoResponse || pr == ReasonConnReset
+}
+
+// classifyError returns the PortReason for the error a probe failed with.
+// The error is classified by the underlying system error number, so the classification doesn't depend on the
+// language of the error messages of the system. Errors without known error number that report a timeout
+// are classified as ReasonNoResponse and all other errors as ReasonError.
+func classifyError(err error) PortReason {
+	if err == nil {
+		return ReasonUnknown
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if reason, ok := errnoReasons[errno]; ok {
+			return reason
+		}
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return ReasonNoResponse
+	}
+	return ReasonError
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// timeoutError is a net.Error without error number that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want PortReason
+	}{
+		{"nil", nil, ReasonUnknown},
+		{"timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, ReasonNoResponse},
+		{"deadline", context.DeadlineExceeded, ReasonNoResponse},
+		{"unknown", errors.New("something went wrong"), ReasonError},
+		{"wrapped unknown", fmt.Errorf("probe: %w", errors.New("something went wrong")), ReasonError},
+	}
+	for errno, reason := range errnoReasons {
+		tests = append(tests, struct {
+			name string
+			err  error
+			want PortReason
+		}{errno.Error(), &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}, reason})
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyRefusedDial(t *testing.T) {
+	_, closed := listenTCPLoopback(t)
+	_, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", fmt.Sprint(closed)), time.Second)
+	if err == nil {
+		t.Skip("connection to closed port was accepted")
+	}
+	if got := classifyError(err); got != ReasonRefused {
+		t.Errorf("classifyError(%v) = %s, want %s", err, got, ReasonRefused)
+	}
+}
+
+func TestPortReasonState(t *testing.T) {
+	tests := []struct {
+		reason PortReason
+		proto  string
+		want   PortState
+	}{
+		{ReasonSynAck, "tcp", PortOpen},
+		{ReasonRefused, "tcp", PortClosed},
+		{ReasonReset, "tcp", PortClosed},
+		{ReasonNoResponse, "tcp", PortFiltered},
+		{ReasonNoResponse, "udp", PortOpenFiltered},
+		{ReasonUDPResponse, "udp", PortOpen},
+		{ReasonPortUnreachable, "udp", PortClosed},
+		{ReasonAdminProhibited, "tcp", PortFiltered},
+		{ReasonResourceExhausted, "tcp", PortError},
+		{ReasonError, "tcp", PortError},
+	}
+	for _, tt := range tests {
+		if got := tt.reason.State(tt.proto); got != tt.want {
+			t.Errorf("%s.State(%q) = %s, want %s", tt.reason, tt.proto, got, tt.want)
+		}
+	}
+}
+
+func TestPortReasonTransient(t *testing.T) {
+	tests := []struct {
+		reason PortReason
+		want   bool
+	}{
+		{ReasonNoResponse, true},
+		{ReasonConnReset, true},
+		{ReasonSynAck, false},
+		{ReasonRefused, false},
+		{ReasonHostUnreachable, false},
+		{ReasonResourceExhausted, false},
+		{ReasonCanceled, false},
+		{ReasonError, false},
+	}
+	for _, tt := range tests {
+		if got := tt.reason.transient(); got != tt.want {
+			t.Errorf("%s.transient() = %v, want %v", tt.reason, got, tt.want)
+		}
+	}
+}
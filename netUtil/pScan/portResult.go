@@ -29,8 +29,8 @@ import (
 )
 
 // PortResult represents the result of a port scan for every port of the target.
-// The open, closed and filtered ports are contained in Open, Closed and Filtered
-// respectively to the outcome of the scan.
+// The open, closed, filtered, open or filtered and errored ports are contained in Open, Closed, Filtered, OpenFiltered
+// and Errored respectively to the outcome of the scan. Every scanned port is contained in exactly one of them.
 type PortResult struct {
 	// Open is a list ports that where determined as open.
 	Open netUtil.Ports
@@ -40,6 +40,37 @@ type PortResult struct {
 
 	// Filtered is a list of ports that where determined as filtered.
 	Filtered netUtil.Ports
+
+	// OpenFiltered is a list of UDP ports that didn't respond at all, so they are either open or filtered.
+	OpenFiltered netUtil.Ports
+
+	// Errored is a list of ports whose state couldn't be determined because of an error.
+	// The error is recorded in the PortDetail of the port.
+	Errored netUtil.Ports
+
+	// Details contains the additional information gathered for single ports, like the detected service version.
+	// It is keyed by the port number and transport protocol (e.g. 80/tcp) and contains an entry for every scanned
+	// port. Use Detail to look up the information for a port.
+	Details map[string]*PortDetail
+}
+
+// PortDetail contains the additional information gathered for a single scanned port.
+type PortDetail struct {
+	// Service contains the detected service if service detection was enabled and the port is open.
+	Service *ServiceInfo
+
+	// TLS contains the TLS parameters and server certificate if TLS inspection was enabled and
+	// the port is open and speaks TLS.
+	TLS *TLSInfo
+
+	// Reason is the reason the port was determined to be in its state.
+	Reason PortReason
+
+	// Attempts is the number of probes sent to the port if it was more than one.
+	Attempts int
+
+	// Err is the error the last probe of an errored port failed with.
+	Err error
 }
 
 // NewPortResult returns a pointer to an uninitialized instance of PortResult.
@@ -47,120 +78,154 @@ func NewPortResult() *PortResult {
 	return &PortResult{}
 }
 
-// String returns a string representation of the PortResult pointer.
-func (p *PortResult) String() string {
-	ret := "*************** PORT RESULT **********************\n"
-	if len(p.Open) > 0 {
-		ret += "Open TCP Ports:\n"
-		for _, oP := range p.Open {
-			if oP.Description == "" {
-				ret += fmt.Sprintf("\t%s %s\n", symbols.OPEN, oP)
-			} else {
-				ret += fmt.Sprintf("\t%s %s - %s\n", symbols.OPEN, oP, strings.Replace(oP.Description, "\n", " ", -1))
-			}
-		}
+// Detail returns the PortDetail of port or nil if there is no additional information about port.
+func (p *PortResult) Detail(port *netUtil.Port) *PortDetail {
+	return p.Details[portKey(port)]
+}
+
+// detail returns the PortDetail of port and creates it if it doesn't exist yet.
+func (p *PortResult) detail(port *netUtil.Port) *PortDetail {
+	if p.Details == nil {
+		p.Details = make(map[string]*PortDetail)
 	}
-	if len(p.Closed) > 0 {
-		ret += "Closed TCP Ports:\n"
-		for _, cP := range p.Closed {
-			if cP.Description == "" {
-				ret += fmt.Sprintf("\t%s %s\n", symbols.CLOSED, cP)
-			} else {
-				ret += fmt.Sprintf("\t%s %s - %s\n", symbols.CLOSED, cP, strings.Replace(cP.Description, "\n", " ", -1))
-			}
-		}
+	d, ok := p.Details[portKey(port)]
+	if !ok {
+		d = &PortDetail{}
+		p.Details[portKey(port)] = d
 	}
-	if len(p.Filtered) > 0 {
-		ret += "Offline or filtered TCP Ports:\n"
-		for _, fP := range p.Filtered {
-			if fP.Description == "" {
-				ret += fmt.Sprintf("\t%s %s\n", symbols.UNKNOWN, fP)
-			} else {
-				ret += fmt.Sprintf("\t%s %s - %s\n", symbols.UNKNOWN, fP, strings.Replace(fP.Description, "\n", " ", -1))
-			}
-		}
+	return d
+}
+
+// Reason returns the PortReason port was determined to be in its state for or ReasonUnknown if it isn't known.
+func (p *PortResult) Reason(port *netUtil.Port) PortReason {
+	if d := p.Detail(port); d != nil {
+		return d.Reason
 	}
-	ret += "**************************************************"
-	return ret
+	return ReasonUnknown
 }
 
-// ColorString returns a colored string representation of the PortResult pointer.
-func (p *PortResult) ColorString() string {
-	ret := "*************** PORT RESULT **********************\n"
-	if len(p.Open) > 0 {
-		ret += "Open TCP Ports:\n"
-		for _, oP := range p.Open {
-			if oP.Description == "" {
-				ret += colorFmt.Sopenf("\t%s %s\n", symbols.OPEN, oP)
-			} else {
-				ret += colorFmt.Sopenf("\t%s %s - %s\n", symbols.OPEN, oP, strings.Replace(oP.Description, "\n", " ", -1))
-			}
+// addError adds port to the errored ports and records reason, err and the number of attempts in its PortDetail.
+func (p *PortResult) addError(port *netUtil.Port, reason PortReason, err error, attempts int) {
+	p.Errored = append(p.Errored, port)
+	d := p.detail(port)
+	d.Reason = reason
+	d.Err = err
+	d.Attempts = attempts
+}
+
+// merge adds the ports and details of other to the PortResult.
+func (p *PortResult) merge(other *PortResult) {
+	p.Open = append(p.Open, other.Open...)
+	p.Closed = append(p.Closed, other.Closed...)
+	p.Filtered = append(p.Filtered, other.Filtered...)
+	p.OpenFiltered = append(p.OpenFiltered, other.OpenFiltered...)
+	p.Errored = append(p.Errored, other.Errored...)
+	for k, d := range other.Details {
+		if p.Details == nil {
+			p.Details = make(map[string]*PortDetail)
 		}
+		p.Details[k] = d
 	}
-	if len(p.Closed) > 0 {
-		ret += "Closed TCP Ports:\n"
-		for _, cP := range p.Closed {
-			if cP.Description == "" {
-				ret += colorFmt.Sclosedf("\t%s %s\n", symbols.CLOSED, cP)
-			} else {
-				ret += colorFmt.Sclosedf("\t%s %s - %s\n", symbols.CLOSED, cP, strings.Replace(cP.Description, "\n", " ", -1))
-			}
-		}
+}
+
+// portKey returns the key of port in PortResult.Details.
+func portKey(port *netUtil.Port) string {
+	return fmt.Sprintf("%d/%s", port.PortNo, port.Protocol)
+}
+
+// each calls f for every port of the PortResult together with the PortState it was determined to be in.
+func (p *PortResult) each(f func(port *netUtil.Port, state PortState)) {
+	for _, oP := range p.Open {
+		f(oP, PortOpen)
 	}
-	if len(p.Filtered) > 0 {
-		ret += "Offline or filtered TCP Ports:\n"
-		for _, fP := range p.Filtered {
-			if fP.Description == "" {
-				ret += colorFmt.Sfilteredf("\t%s %s\n", symbols.UNKNOWN, fP)
-			} else {
-				ret += colorFmt.Sfilteredf("\t%s %s - %s\n", symbols.UNKNOWN, fP, strings.Replace(fP.Description, "\n", " ", -1))
-			}
-		}
+	for _, cP := range p.Closed {
+		f(cP, PortClosed)
+	}
+	for _, fP := range p.Filtered {
+		f(fP, PortFiltered)
+	}
+	for _, ofP := range p.OpenFiltered {
+		f(ofP, PortOpenFiltered)
+	}
+	for _, eP := range p.Errored {
+		f(eP, PortError)
 	}
-	ret += "**************************************************"
-	return ret
+}
+
+// String returns a string representation of the PortResult pointer.
+func (p *PortResult) String() string {
+	return p.render(fmt.Sprintf, fmt.Sprintf, fmt.Sprintf, true)
+}
+
+// ColorString returns a colored string representation of the PortResult pointer.
+func (p *PortResult) ColorString() string {
+	return p.render(colorFmt.Sopenf, colorFmt.Sclosedf, colorFmt.Sfilteredf, true)
 }
 
 // CustomColorString returns a colored string representation of the PortResult pointer.
 // The parameter showClosed controls if closed and filtered ports also will be incorporated into the string.
 func (p *PortResult) CustomColorString(showClosed bool) string {
+	return p.render(colorFmt.Sopenf, colorFmt.Sclosedf, colorFmt.Sfilteredf, showClosed)
+}
+
+// render returns a string representation of the PortResult pointer where the ports are grouped by state and
+// transport protocol. The lines of open, closed and filtered ports are formatted with openf, closedf and filteredf.
+// showClosed controls if closed and filtered ports also will be incorporated into the string.
+func (p *PortResult) render(openf, closedf, filteredf func(format string, a ...interface{}) string, showClosed bool) string {
 	ret := "*************** PORT RESULT **********************\n"
-	if len(p.Open) > 0 {
-		ret += "Open TCP Ports:\n"
-		for _, oP := range p.Open {
-			if oP.Description == "" {
-				ret += colorFmt.Sopenf("\t%s %s\n", symbols.OPEN, oP)
-			} else {
-				ret += colorFmt.Sopenf("\t%s %s - %s\n", symbols.OPEN, oP, strings.Replace(oP.Description, "\n", " ", -1))
-			}
-		}
+	for _, proto := range portProtocols {
+		ret += p.renderPorts(fmt.Sprintf("Open %s Ports", strings.ToUpper(proto)), symbols.OPEN, p.Open, proto, openf)
 	}
 	if showClosed {
-		if len(p.Closed) > 0 {
-			ret += "Closed TCP Ports:\n"
-			for _, cP := range p.Closed {
-				if cP.Description == "" {
-					ret += colorFmt.Sclosedf("\t%s %s\n", symbols.CLOSED, cP)
-				} else {
-					ret += colorFmt.Sclosedf("\t%s %s - %s\n", symbols.CLOSED, cP, strings.Replace(cP.Description, "\n", " ", -1))
-				}
-			}
+		for _, proto := range portProtocols {
+			ret += p.renderPorts(fmt.Sprintf("Closed %s Ports", strings.ToUpper(proto)), symbols.CLOSED, p.Closed, proto, closedf)
 		}
-		if len(p.Filtered) > 0 {
-			ret += "Offline or filtered TCP Ports:\n"
-			for _, fP := range p.Filtered {
-				if fP.Description == "" {
-					ret += colorFmt.Sfilteredf("\t%s %s\n", symbols.UNKNOWN, fP)
-				} else {
-					ret += colorFmt.Sfilteredf("\t%s %s - %s\n", symbols.UNKNOWN, fP, strings.Replace(fP.Description, "\n", " ", -1))
-				}
-			}
+		for _, proto := range portProtocols {
+			title := fmt.Sprintf("Offline or filtered %s Ports", strings.ToUpper(proto))
+			ret += p.renderPorts(title, symbols.UNKNOWN, p.Filtered, proto, filteredf)
+		}
+		// UDP ports that don't respond at all can as well be open
+		ret += p.renderPorts("Open or filtered UDP Ports", symbols.UNKNOWN, p.OpenFiltered, "udp", filteredf)
+		for _, proto := range portProtocols {
+			ret += p.renderPorts(fmt.Sprintf("Errored %s Ports", strings.ToUpper(proto)), symbols.UNKNOWN, p.Errored, proto, filteredf)
 		}
 	}
 	ret += "**************************************************"
 	return ret
 }
 
+// portProtocols contains the transport protocols in the order they appear in the string representation of PortResult.
+var portProtocols = []string{"tcp", "udp"}
+
+// renderPorts returns a line formatted with sprintf for every port in ps that uses the transport protocol proto.
+// If there is at least one such port, the lines are preceded by title. Detected services are appended to the line.
+func (p *PortResult) renderPorts(title, symbol string, ps netUtil.Ports, proto string, sprintf func(format string, a ...interface{}) string) string {
+	ret := ""
+	for _, port := range ps {
+		if port.Protocol != proto {
+			continue
+		}
+		if ret == "" {
+			ret = title + ":\n"
+		}
+		if port.Description == "" {
+			ret += sprintf("\t%s %s\n", symbol, port)
+		} else {
+			ret += sprintf("\t%s %s - %s\n", symbol, port, strings.Replace(port.Description, "\n", " ", -1))
+		}
+		if d := p.Detail(port); d != nil && d.Service != nil {
+			ret += sprintf("\t\tService: %s\n", d.Service)
+		}
+		if d := p.Detail(port); d != nil && d.TLS != nil {
+			ret += sprintf("\t\tTLS: %s\n", strings.Replace(d.TLS.String(), "\n", "\n\t\t     ", -1))
+		}
+		if d := p.Detail(port); d != nil && d.Err != nil {
+			ret += sprintf("\t\tError: %s (%d attempts)\n", d.Err, d.Attempts)
+		}
+	}
+	return ret
+}
+
 /*
 func (p *PortResult) String() string {
 	var c int
@@ -0,0 +1,191 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"github.com/ElCap1tan/gort/internal/helper/ulimit"
+	"github.com/ElCap1tan/gort/netUtil"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// scanEngine scans the ports of any number of targets with a bounded pool of workers. The (host, port) jobs of the
+// workers are generated lazily one target after another, so the memory use of a scan depends on the size of the
+// worker pool and the number of targets scanned simultaneously, but not on the total number of targets or ports.
+type scanEngine struct {
+	env  *scanEnv
+	jobs chan scanJob
+
+	// hostLock limits the number of targets scanned simultaneously. It is nil if there is no limit.
+	hostLock *semaphore.Weighted
+
+	maxWorkers int
+	workers    int
+	workerWg   sync.WaitGroup
+}
+
+// scanJob is a single port of a target that is scanned by a worker of the scanEngine.
+type scanJob struct {
+	host *hostScan
+	port *netUtil.Port
+}
+
+// hostScan contains the state of the scan of a single target that is shared by the workers scanning its ports.
+type hostScan struct {
+	target    *Target
+	ctx       context.Context
+	cancel    context.CancelFunc
+	estimator *timeoutEstimator
+	onPort    func(e *PortEvent)
+	finish    func(r *ScanResult)
+
+	mu      sync.Mutex
+	result  *ScanResult
+	pending int
+}
+
+// newScanEngine returns a pointer to a new scanEngine that scans with the settings and resources of env.
+func newScanEngine(env *scanEnv) *scanEngine {
+	e := &scanEngine{env: env, jobs: make(chan scanJob), maxWorkers: maxScanWorkers(env.opts.Concurrency)}
+	if env.opts.MaxHosts > 0 {
+		e.hostLock = semaphore.NewWeighted(int64(env.opts.MaxHosts))
+	}
+	return e
+}
+
+// maxScanWorkers returns the size of the worker pool for the given concurrency, which is never more than
+// the maximum number of open files allowed by the system. A zero concurrency means no further limit.
+func maxScanWorkers(concurrency int) int {
+	limit := 1024
+	if l, err := ulimit.GetUlimit(); err == nil {
+		limit = int(l)
+	}
+	if concurrency > 0 && concurrency < limit {
+		limit = concurrency
+	}
+	return limit
+}
+
+// run scans the ports of every target returned by next until next returns nil. The ScanResult of every target is
+// sent over out as soon as the scan of the target is finished and out is closed once all targets are scanned.
+// If ctx is done, the remaining ports are reported as errored, so there still is a result for every target.
+// The ports of excluded targets are never scanned.
+// run returns when all targets are scanned and releases the resources of the scan environment.
+func (e *scanEngine) run(ctx context.Context, next func() *Target, out chan<- *ScanResult) {
+	for t := next(); t != nil; t = next() {
+		release := func() {}
+		if e.hostLock != nil && e.hostLock.Acquire(ctx, 1) == nil {
+			// Targets that can't get a slot anymore are still scanned, which reports their ports as errored.
+			release = func() { e.hostLock.Release(1) }
+		}
+		ports := t.Ports.Unique()
+		if t.Excluded() || e.env.opts.SkipDown && t.Down() {
+			ports = nil
+		}
+		h := e.startHost(ctx, t, len(ports), func(r *ScanResult) {
+			release()
+			out <- r
+		})
+		if len(ports) == 0 {
+			h.done()
+			continue
+		}
+		for _, p := range ports {
+			e.submit(scanJob{host: h, port: p})
+		}
+	}
+	close(e.jobs)
+	e.workerWg.Wait()
+	e.env.close()
+	close(out)
+}
+
+// startHost returns a pointer to the hostScan of t with the given number of ports. finish is called with the result
+// once all ports are scanned.
+func (e *scanEngine) startHost(ctx context.Context, t *Target, ports int, finish func(r *ScanResult)) *hostScan {
+	opts := e.env.opts
+	h := &hostScan{target: t, onPort: opts.OnPort, finish: finish, result: NewScanResult(t, time.Now()), pending: ports}
+	if opts.HostTimeout > 0 {
+		h.ctx, h.cancel = context.WithTimeout(ctx, opts.HostTimeout)
+	} else {
+		h.ctx, h.cancel = context.WithCancel(ctx)
+	}
+	if e.env.syn != nil && t.IPAddr.To4() != nil {
+		h.result.Technique = SynScan
+	}
+	if opts.AdaptiveTimeout {
+		h.estimator = newTimeoutEstimator(opts.MinTimeout, opts.MaxTimeout, t.RTTs)
+	}
+	return h
+}
+
+// submit hands job over to an idle worker. If all workers are busy and the pool isn't full yet, a new worker is
+// started, otherwise submit blocks until a worker is idle. submit must only be called by the goroutine of run.
+func (e *scanEngine) submit(job scanJob) {
+	select {
+	case e.jobs <- job:
+		return
+	default:
+	}
+	if e.workers < e.maxWorkers {
+		e.workers++
+		e.workerWg.Add(1)
+		go e.work()
+	}
+	e.jobs <- job
+}
+
+// work scans the jobs of the scanEngine until there are no jobs left.
+func (e *scanEngine) work() {
+	defer e.workerWg.Done()
+	for job := range e.jobs {
+		h := job.host
+		h.add(h.target.scanPort(h.ctx, job.port, e.env, h.estimator))
+	}
+}
+
+// add merges the result of a single port into the result of the hostScan and reports the scanned ports to
+// the OnPort callback. Once all ports are scanned, the hostScan is finished. It is safe for concurrent use.
+func (h *hostScan) add(pI *PortResult) {
+	h.mu.Lock()
+	h.result.Ports.merge(pI)
+	if h.onPort != nil {
+		pI.each(func(p *netUtil.Port, state PortState) {
+			h.onPort(&PortEvent{Target: h.target, Port: p, State: state, Reason: pI.Reason(p), Detail: pI.Detail(p), Time: time.Now()})
+		})
+	}
+	h.pending--
+	finished := h.pending == 0
+	h.mu.Unlock()
+	if finished {
+		h.done()
+	}
+}
+
+// done finishes the hostScan and hands its result over.
+func (h *hostScan) done() {
+	h.cancel()
+	h.result.EndTime = time.Now()
+	h.finish(h.result)
+}
@@ -22,9 +22,23 @@ package pScan
 
 import (
 	"fmt"
+	"io"
 	"time"
 )
 
+// The banners enclosing the string representations of MultiScanResult.
+const (
+	multiScanResultHeader = "" +
+		"#################################################################\n" +
+		"############### MULTI SCAN RESULT ###############################\n" +
+		"#################################################################\n\n"
+	unresolvedHeader = "\n" +
+		"#################################################################\n" +
+		"############### UNRESOLVED ######################################\n" +
+		"#################################################################\n\n"
+	multiScanResultFooter = "\n#################################################################"
+)
+
 // ScanResult represents the result of a single port scan.
 // It contains the scans StartTime and EndTime, the scan Target and the PortResult.
 type ScanResult struct {
@@ -39,6 +53,9 @@ type ScanResult struct {
 
 	// Ports is the PortResult of the scan.
 	Ports *PortResult
+
+	// Technique is the ScanTechnique the TCP ports of the Target were scanned with.
+	Technique ScanTechnique
 }
 
 // ScanResults is an array of ScanResult pointers.
@@ -51,6 +68,9 @@ type MultiScanResult struct {
 
 	// Unresolved contains the unresolved Targets
 	Unresolved Targets
+
+	// Args contains the command line arguments the scan was started with, if known.
+	Args []string
 }
 
 // NewScanResult returns the pointer to a new ScanResult instance.
@@ -104,53 +124,41 @@ func (s *ScanResult) CustomColorString(showClosed bool) string {
 
 // String returns a string representation of the MultiScanResult pointer.
 func (m *MultiScanResult) String() string {
-	ret := "" +
-		"#################################################################\n" +
-		"############### MULTI SCAN RESULT ###############################\n" +
-		"#################################################################\n\n"
+	ret := multiScanResultHeader
 	if len(m.Resolved) == 0 {
 		ret += "\tNONE\n"
 	}
 	for _, scanResult := range m.Resolved {
 		ret += scanResult.String() + "\n\n"
 	}
-	ret += "\n" +
-		"#################################################################\n" +
-		"############### UNRESOLVED ######################################\n" +
-		"#################################################################\n\n"
+	ret += unresolvedHeader
 	if len(m.Unresolved) == 0 {
 		ret += "\tNONE\n"
 	}
 	for _, target := range m.Unresolved {
 		ret += target.String() + "\n\n"
 	}
-	ret += "\n#################################################################"
+	ret += multiScanResultFooter
 	return ret
 }
 
 // ColorString returns a colored string representation of the ScanResult pointer.
 func (m *MultiScanResult) ColorString() string {
-	ret := "" +
-		"#################################################################\n" +
-		"############### MULTI SCAN RESULT ###############################\n" +
-		"#################################################################\n\n"
+	ret := multiScanResultHeader
 	if len(m.Resolved) == 0 {
 		ret += "\tNONE\n"
 	}
 	for _, scanResult := range m.Resolved {
 		ret += scanResult.ColorString() + "\n\n"
 	}
-	ret += "\n" +
-		"#################################################################\n" +
-		"############### UNRESOLVED ######################################\n" +
-		"#################################################################\n\n"
+	ret += unresolvedHeader
 	if len(m.Unresolved) == 0 {
 		ret += "\tNONE\n"
 	}
 	for _, target := range m.Unresolved {
 		ret += target.ColorString() + "\n\n"
 	}
-	ret += "\n#################################################################"
+	ret += multiScanResultFooter
 	return ret
 }
 
@@ -158,10 +166,7 @@ func (m *MultiScanResult) ColorString() string {
 // The parameter onlineOnly controls if targets not confirmed as online will be incorporated into the string.
 // showClosed controls if closed and filtered ports also will be incorporated into the string.
 func (m *MultiScanResult) CustomColorString(onlineOnly, showClosed bool) string {
-	ret := "" +
-		"#################################################################\n" +
-		"############### MULTI SCAN RESULT ###############################\n" +
-		"#################################################################\n\n"
+	ret := multiScanResultHeader
 	if len(m.Resolved) == 0 {
 		ret += "\tNONE\n"
 	}
@@ -175,10 +180,7 @@ func (m *MultiScanResult) CustomColorString(onlineOnly, showClosed bool) string
 		}
 	}
 	if !onlineOnly {
-		ret += "\n" +
-			"#################################################################\n" +
-			"############### UNRESOLVED ######################################\n" +
-			"#################################################################\n\n"
+		ret += unresolvedHeader
 		if len(m.Unresolved) == 0 {
 			ret += "\tNONE\n"
 		}
@@ -186,6 +188,59 @@ func (m *MultiScanResult) CustomColorString(onlineOnly, showClosed bool) string
 			ret += target.ColorString() + "\n\n"
 		}
 	}
-	ret += "\n#################################################################"
+	ret += multiScanResultFooter
 	return ret
 }
+
+// StreamPrinter writes colored string representations of ScanResults to an io.Writer as soon as they are available.
+// The output has the same layout as the one of MultiScanResult.CustomColorString, which makes it possible to
+// print the results of a streamed scan started with Targets.ScanStream while it is still running.
+type StreamPrinter struct {
+	w          io.Writer
+	onlineOnly bool
+	showClosed bool
+	printed    int
+}
+
+// NewStreamPrinter returns a pointer to a new StreamPrinter writing to w.
+// The parameter onlineOnly controls if targets not confirmed as online will be printed.
+// showClosed controls if closed and filtered ports also will be printed.
+func NewStreamPrinter(w io.Writer, onlineOnly, showClosed bool) *StreamPrinter {
+	return &StreamPrinter{w: w, onlineOnly: onlineOnly, showClosed: showClosed}
+}
+
+// Start writes the header of the output and should be called before the first call to StreamPrinter.Write.
+func (sp *StreamPrinter) Start() error {
+	_, err := io.WriteString(sp.w, multiScanResultHeader)
+	return err
+}
+
+// Write writes the colored string representation of the ScanResult s.
+func (sp *StreamPrinter) Write(s *ScanResult) error {
+	if sp.onlineOnly && s.Target.Status != Online {
+		return nil
+	}
+	sp.printed++
+	_, err := io.WriteString(sp.w, s.CustomColorString(sp.showClosed)+"\n\n")
+	return err
+}
+
+// Finish writes the unresolved targets followed by the footer of the output.
+func (sp *StreamPrinter) Finish(unresolved Targets) error {
+	ret := ""
+	if sp.printed == 0 {
+		ret += "\tNONE\n"
+	}
+	if !sp.onlineOnly {
+		ret += unresolvedHeader
+		if len(unresolved) == 0 {
+			ret += "\tNONE\n"
+		}
+		for _, target := range unresolved {
+			ret += target.ColorString() + "\n\n"
+		}
+	}
+	ret += multiScanResultFooter
+	_, err := io.WriteString(sp.w, ret+"\n")
+	return err
+}
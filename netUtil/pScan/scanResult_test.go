@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"github.com/ElCap1tan/gort/netUtil"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with the golden file name in testdata and fails the test if they differ.
+// If the tests are run with -update, the golden file is replaced by got instead.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+// newTestMultiScanResult returns a hand made MultiScanResult with two resolved targets and an unresolved one,
+// which is used as input of the output writer tests.
+func newTestMultiScanResult() MultiScanResult {
+	start := time.Date(2020, time.October, 11, 12, 0, 0, 0, time.UTC)
+	mac, _ := net.ParseMAC("08:00:27:12:34:56")
+
+	web := &Target{
+		InitialTarget: "web.example.com",
+		HostName:      "web.example.com.",
+		IPAddr:        net.ParseIP("192.0.2.10"),
+		MACAddr:       mac,
+		Vendor:        "PCS Systemtechnik GmbH",
+		Status:        Online,
+		Location:      Local,
+		RTTs:          []time.Duration{1500 * time.Microsecond, 2500 * time.Microsecond},
+	}
+	webPorts := NewPortResult()
+	webPorts.Open = netUtil.Ports{
+		netUtil.NewPort(22, "tcp", "ssh", "The Secure Shell (SSH) Protocol"),
+		netUtil.NewPort(80, "tcp", "http", "World Wide Web HTTP"),
+		netUtil.NewPort(443, "tcp", "https", "http protocol over TLS/SSL"),
+	}
+	webPorts.Closed = netUtil.Ports{netUtil.NewPort(23, "tcp", "telnet", "Telnet")}
+	webPorts.Filtered = netUtil.Ports{netUtil.NewPort(8081, "tcp", "N/A", "No description available")}
+	webPorts.OpenFiltered = netUtil.Ports{netUtil.NewPort(53, "udp", "domain", "Domain Name Server")}
+	webPorts.each(func(p *netUtil.Port, state PortState) {
+		webPorts.detail(p).Reason = map[PortState]PortReason{
+			PortOpen: ReasonSynAck, PortClosed: ReasonRefused, PortFiltered: ReasonNoResponse, PortOpenFiltered: ReasonNoResponse,
+		}[state]
+	})
+	webPorts.detail(webPorts.Open[0]).Service = &ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "8.4p1",
+		ExtraInfo: "protocol 2.0", Banner: "SSH-2.0-OpenSSH_8.4p1 Debian-5", Probe: "NULL"}
+	webPorts.detail(webPorts.Open[2]).TLS = &TLSInfo{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256",
+		Subject: "CN=web.example.com", SANs: []string{"web.example.com", "192.0.2.10"}, Issuer: "CN=web.example.com",
+		NotBefore: start.AddDate(0, -1, 0), NotAfter: start.AddDate(1, 0, 0), KeyType: "ECDSA", KeyBits: 256,
+		SelfSigned: true}
+
+	gateway := &Target{
+		InitialTarget: "192.0.2.1",
+		HostName:      "N/A",
+		IPAddr:        net.ParseIP("192.0.2.1"),
+		Vendor:        "N/A",
+		Status:        OfflineFiltered,
+		Location:      Global,
+	}
+	gatewayPorts := NewPortResult()
+	gatewayPorts.Closed = netUtil.Ports{netUtil.NewPort(443, "tcp", "https", "http protocol over TLS/SSL")}
+	gatewayPorts.detail(gatewayPorts.Closed[0]).Reason = ReasonRefused
+	gatewayPorts.addError(netUtil.NewPort(8443, "tcp", "pcsync-https", "PCsync HTTPS"), ReasonNetUnreachable,
+		errors.New("dial tcp 192.0.2.1:8443: connect: network is unreachable"), 3)
+
+	return MultiScanResult{
+		Resolved: ScanResults{
+			{StartTime: start, EndTime: start.Add(2 * time.Second), Target: web, Ports: webPorts},
+			{StartTime: start.Add(time.Second), EndTime: start.Add(4 * time.Second), Target: gateway, Ports: gatewayPorts},
+		},
+		Unresolved: Targets{{InitialTarget: "unknown.invalid", HostName: "N/A", Vendor: "N/A", Status: Unknown,
+			Location: UnknownLoc}},
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"github.com/ElCap1tan/gort/netUtil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// serviceReadTimeout is the maximum time to wait for a response to a service probe.
+const serviceReadTimeout = 2 * time.Second
+
+// serviceMaxResponse is the maximum number of bytes read as response to a service probe.
+const serviceMaxResponse = 4096
+
+// ServiceInfo contains the information about the service detected on an open port.
+type ServiceInfo struct {
+	// Name is the name of the detected service (e.g. ssh or http).
+	// It is empty if the port responded but the response didn't match any known signature.
+	Name string
+
+	// Product is the name of the software running the service if known.
+	Product string
+
+	// Version is the version of the software running the service if known.
+	Version string
+
+	// ExtraInfo contains additional information about the service like the operating system or protocol version.
+	ExtraInfo string
+
+	// Banner is the first line of the response of the service with all non-printable characters replaced.
+	Banner string
+
+	// Probe is the name of the probe that got the response the service was detected with.
+	Probe string
+}
+
+// String returns a string representation of the ServiceInfo pointer.
+func (s *ServiceInfo) String() string {
+	var parts []string
+	if s.Name == "" {
+		parts = append(parts, "unrecognized")
+	} else {
+		parts = append(parts, s.Name)
+	}
+	if s.Product != "" {
+		parts = append(parts, s.Product)
+	}
+	if s.Version != "" {
+		parts = append(parts, s.Version)
+	}
+	if s.ExtraInfo != "" {
+		parts = append(parts, "("+s.ExtraInfo+")")
+	}
+	if s.Name == "" && s.Banner != "" {
+		parts = append(parts, "\""+s.Banner+"\"")
+	}
+	return strings.Join(parts, " ")
+}
+
+// versionString returns the product and version of the ServiceInfo pointer separated by a space.
+func (s *ServiceInfo) versionString() string {
+	return strings.TrimSpace(s.Product + " " + s.Version)
+}
+
+// serviceProbe is a payload that is sent to an open port to elicit a response from the service running on it.
+type serviceProbe struct {
+	// name is the name of the probe.
+	name string
+
+	// payload is the data that is sent to the port.
+	payload []byte
+
+	// ports contains the ports the probe is tried first on.
+	ports []uint16
+
+	// afterBanner is the prefix of banners the probe is sent after on the same connection.
+	// Probes without afterBanner are sent on a new connection to ports that don't send a banner by themselves.
+	afterBanner string
+}
+
+// serviceProbes contains the probes sent to open ports in order.
+var serviceProbes = []*serviceProbe{
+	{name: "SMTPEhlo", payload: []byte("EHLO gort\r\n"), ports: []uint16{25, 465, 587}, afterBanner: "220"},
+	{name: "HTTPHead", payload: []byte("HEAD / HTTP/1.0\r\n\r\n"), ports: []uint16{80, 81, 591, 8000, 8008, 8080, 8081, 8443, 8888, 9000}},
+	{name: "RedisPing", payload: []byte("*1\r\n$4\r\nPING\r\n"), ports: []uint16{6379}},
+	{name: "GenericLines", payload: []byte("\r\n\r\n")},
+}
+
+// detectService tries to detect the service running on port p of the Target. conn is a established connection
+// to the port. It is first used to read the banner the service sends by itself. If there is no banner, the
+// serviceProbes are sent until one of them gets a response. The response is matched against serviceSignatures.
+// nil is returned if the service never responded.
+func (t *Target) detectService(ctx context.Context, conn net.Conn, p *netUtil.Port) *ServiceInfo {
+	resp := readServiceResponse(ctx, conn)
+	if len(resp) > 0 {
+		probe := "NULL"
+		for _, sp := range serviceProbes {
+			if sp.afterBanner != "" && strings.HasPrefix(string(resp), sp.afterBanner) && sp.prefers(p.PortNo) {
+				if _, err := conn.Write(sp.payload); err == nil {
+					resp = append(resp, readServiceResponse(ctx, conn)...)
+					probe = sp.name
+				}
+				break
+			}
+		}
+		return matchService(resp, probe)
+	}
+
+	addr := net.JoinHostPort(t.IPAddr.String(), strconv.Itoa(int(p.PortNo)))
+	for _, sp := range orderedServiceProbes(p.PortNo) {
+		dialer := net.Dialer{Timeout: serviceReadTimeout}
+		c, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil
+		}
+		_, err = c.Write(sp.payload)
+		if err == nil {
+			resp = readServiceResponse(ctx, c)
+		}
+		_ = c.Close()
+		if len(resp) > 0 {
+			return matchService(resp, sp.name)
+		}
+	}
+	return nil
+}
+
+// prefers returns true if port is one of the ports the serviceProbe is tried first on.
+func (sp *serviceProbe) prefers(port uint16) bool {
+	for _, p := range sp.ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// orderedServiceProbes returns the serviceProbes that are sent on a new connection to port.
+// Probes that prefer port come first.
+func orderedServiceProbes(port uint16) []*serviceProbe {
+	var preferred, others []*serviceProbe
+	for _, sp := range serviceProbes {
+		if sp.afterBanner != "" {
+			continue
+		}
+		if sp.prefers(port) {
+			preferred = append(preferred, sp)
+		} else {
+			others = append(others, sp)
+		}
+	}
+	return append(preferred, others...)
+}
+
+// readServiceResponse reads the response of a service from conn until serviceMaxResponse bytes are read,
+// the connection is closed or no more data arrives. The read is aborted when ctx is done.
+func readServiceResponse(ctx context.Context, conn net.Conn) []byte {
+	deadline := time.Now().Add(serviceReadTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetReadDeadline(deadline)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Unblocks the pending read
+			_ = conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	var resp []byte
+	buf := make([]byte, serviceMaxResponse)
+	for len(resp) < serviceMaxResponse {
+		n, err := conn.Read(buf[:serviceMaxResponse-len(resp)])
+		resp = append(resp, buf[:n]...)
+		if err != nil {
+			break
+		}
+		// Once the first data arrived only wait shortly for the rest of the response.
+		if d := time.Now().Add(200 * time.Millisecond); d.Before(deadline) {
+			_ = conn.SetReadDeadline(d)
+		}
+	}
+	return resp
+}
+
+// matchService matches resp against serviceSignatures and returns the detected service.
+// probe is the name of the probe resp was received for.
+func matchService(resp []byte, probe string) *ServiceInfo {
+	info := &ServiceInfo{Banner: bannerLine(resp), Probe: probe}
+	for _, sig := range serviceSignatures {
+		match := sig.pattern.FindSubmatchIndex(resp)
+		if match == nil {
+			continue
+		}
+		info.Name = sig.service
+		info.Product = expandSignature(sig, resp, match, sig.product)
+		info.Version = expandSignature(sig, resp, match, sig.version)
+		info.ExtraInfo = expandSignature(sig, resp, match, sig.extraInfo)
+		break
+	}
+	return info
+}
+
+// bannerLine returns the first non-empty line of resp with all non-printable characters replaced by dots.
+// The line is truncated to 128 characters.
+func bannerLine(resp []byte) string {
+	for _, line := range strings.Split(string(resp), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		line = strings.Map(func(r rune) rune {
+			if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+				return '.'
+			}
+			return r
+		}, line)
+		if len(line) > 128 {
+			line = line[:128]
+		}
+		return line
+	}
+	return ""
+}
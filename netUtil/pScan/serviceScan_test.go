@@ -0,0 +1,179 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"github.com/ElCap1tan/gort/netUtil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchService(t *testing.T) {
+	tests := []struct {
+		resp string
+		want ServiceInfo
+	}{
+		{"SSH-2.0-OpenSSH_8.4p1 Debian-5\r\n",
+			ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "8.4p1", ExtraInfo: "protocol 2.0"}},
+		{"SSH-2.0-dropbear_2020.81\r\n",
+			ServiceInfo{Name: "ssh", Product: "Dropbear sshd", Version: "2020.81", ExtraInfo: "protocol 2.0"}},
+		{"220 (vsFTPd 3.0.3)\r\n", ServiceInfo{Name: "ftp", Product: "vsftpd", Version: "3.0.3"}},
+		{"220 mail.example.com ESMTP Postfix (Debian/GNU)\r\n", ServiceInfo{Name: "smtp", Product: "Postfix smtpd"}},
+		{"HTTP/1.1 200 OK\r\nDate: Sun, 11 Oct 2020 12:00:00 GMT\r\nServer: nginx/1.18.0\r\n\r\n",
+			ServiceInfo{Name: "http", Product: "nginx", Version: "1.18.0"}},
+		{"HTTP/1.1 200 OK\r\nServer: Apache/2.4.46 (Debian)\r\n\r\n",
+			ServiceInfo{Name: "http", Product: "Apache httpd", Version: "2.4.46", ExtraInfo: "Debian"}},
+		{"HTTP/1.0 404 Not Found\r\nServer: gws\r\n\r\n", ServiceInfo{Name: "http", Product: "gws"}},
+		{"HTTP/1.0 400 Bad Request\r\n\r\n", ServiceInfo{Name: "http"}},
+		{"+PONG\r\n", ServiceInfo{Name: "redis", Product: "Redis key-value store"}},
+		{"RFB 003.008\n", ServiceInfo{Name: "vnc", Product: "VNC", ExtraInfo: "protocol 003.008"}},
+		{"hello world\r\n", ServiceInfo{}},
+	}
+	for _, tt := range tests {
+		got := matchService([]byte(tt.resp), "NULL")
+		tt.want.Banner = bannerLine([]byte(tt.resp))
+		tt.want.Probe = "NULL"
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("matchService(%q) = %+v, want %+v", tt.resp, *got, tt.want)
+		}
+	}
+}
+
+func TestBannerLine(t *testing.T) {
+	tests := []struct {
+		resp, want string
+	}{
+		{"SSH-2.0-OpenSSH_8.4p1\r\n", "SSH-2.0-OpenSSH_8.4p1"},
+		{"\r\n\r\n220 ready\r\nsecond line\r\n", "220 ready"},
+		{"bin\x00ary\x01\r\n", "bin.ary."},
+		{strings.Repeat("a", 200), strings.Repeat("a", 128)},
+		{"\r\n", ""},
+	}
+	for _, tt := range tests {
+		if got := bannerLine([]byte(tt.resp)); got != tt.want {
+			t.Errorf("bannerLine(%q) = %q, want %q", tt.resp, got, tt.want)
+		}
+	}
+}
+
+func TestServiceInfoString(t *testing.T) {
+	tests := []struct {
+		info ServiceInfo
+		want string
+	}{
+		{ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "8.4p1", ExtraInfo: "protocol 2.0"},
+			"ssh OpenSSH 8.4p1 (protocol 2.0)"},
+		{ServiceInfo{Name: "http"}, "http"},
+		{ServiceInfo{Banner: "hello world"}, `unrecognized "hello world"`},
+	}
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestOrderedServiceProbes(t *testing.T) {
+	tests := []struct {
+		port uint16
+		want []string
+	}{
+		{8080, []string{"HTTPHead", "RedisPing", "GenericLines"}},
+		{6379, []string{"RedisPing", "HTTPHead", "GenericLines"}},
+		// Probes that are sent after a banner are never sent on a new connection.
+		{25, []string{"HTTPHead", "RedisPing", "GenericLines"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, sp := range orderedServiceProbes(tt.port) {
+			got = append(got, sp.name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("orderedServiceProbes(%d) = %v, want %v", tt.port, got, tt.want)
+		}
+	}
+}
+
+// serveTCPLoopback starts a TCP listener on the loopback interface that handles every connection with handle and
+// returns the listener.
+func serveTCPLoopback(t *testing.T, handle func(conn net.Conn)) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDetectService(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(conn net.Conn)
+		want   ServiceInfo
+	}{
+		{"banner", func(conn net.Conn) {
+			_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_8.4p1 Debian-5\r\n"))
+			_, _ = conn.Read(make([]byte, 1))
+		}, ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "8.4p1", ExtraInfo: "protocol 2.0",
+			Banner: "SSH-2.0-OpenSSH_8.4p1 Debian-5", Probe: "NULL"}},
+		{"probe", func(conn net.Conn) {
+			buf := make([]byte, 512)
+			n, _ := conn.Read(buf)
+			if strings.HasPrefix(string(buf[:n]), "HEAD / ") {
+				_, _ = conn.Write([]byte("HTTP/1.0 200 OK\r\nServer: nginx/1.18.0\r\n\r\n"))
+			}
+		}, ServiceInfo{Name: "http", Product: "nginx", Version: "1.18.0", Banner: "HTTP/1.0 200 OK",
+			Probe: "HTTPHead"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := serveTCPLoopback(t, tt.handle)
+			addr := l.Addr().(*net.TCPAddr)
+			target := &Target{IPAddr: addr.IP}
+			conn, err := net.Dial("tcp", addr.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			got := target.detectService(context.Background(), conn, netUtil.NewPort(uint16(addr.Port), "tcp", "N/A",
+				"No description available"))
+			if got == nil || !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("detectService() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import "regexp"
+
+// serviceSignature describes the response of a known service.
+type serviceSignature struct {
+	// service is the name of the service.
+	service string
+
+	// pattern is the regular expression the response of the service has to match.
+	pattern *regexp.Regexp
+
+	// product, version and extraInfo are templates for the fields of ServiceInfo.
+	// They can reference the submatches of pattern with $1, $2 and so on.
+	product   string
+	version   string
+	extraInfo string
+}
+
+// serviceSignatures contains the signatures of known services. The responses to the service probes are
+// matched against the signatures in order, so more specific signatures must come before generic ones.
+var serviceSignatures = []*serviceSignature{
+	// SSH
+	{service: "ssh", pattern: regexp.MustCompile(`^SSH-([\d.]+)-OpenSSH[_-]([\w.]+)`),
+		product: "OpenSSH", version: "$2", extraInfo: "protocol $1"},
+	{service: "ssh", pattern: regexp.MustCompile(`^SSH-([\d.]+)-dropbear_([\w.]+)`),
+		product: "Dropbear sshd", version: "$2", extraInfo: "protocol $1"},
+	{service: "ssh", pattern: regexp.MustCompile(`^SSH-([\d.]+)-([^\s_-]+)[_-]?(\S*)`),
+		product: "$2", version: "$3", extraInfo: "protocol $1"},
+
+	// FTP
+	{service: "ftp", pattern: regexp.MustCompile(`^220[ -].*\(vsFTPd ([\w.]+)\)`),
+		product: "vsftpd", version: "$1"},
+	{service: "ftp", pattern: regexp.MustCompile(`^220[ -]ProFTPD ([\w.]+)`),
+		product: "ProFTPD", version: "$1"},
+	{service: "ftp", pattern: regexp.MustCompile(`^220[ -].*Pure-FTPd`),
+		product: "Pure-FTPd"},
+	{service: "ftp", pattern: regexp.MustCompile(`^220[ -].*FileZilla Server(?: version)? ([\w.]+)`),
+		product: "FileZilla ftpd", version: "$1"},
+	{service: "ftp", pattern: regexp.MustCompile(`^220[ -].*Microsoft FTP Service`),
+		product: "Microsoft ftpd"},
+	{service: "ftp", pattern: regexp.MustCompile(`(?i)^220[ -].*ftp`)},
+
+	// SMTP
+	{service: "smtp", pattern: regexp.MustCompile(`^220 .*ESMTP Postfix`),
+		product: "Postfix smtpd"},
+	{service: "smtp", pattern: regexp.MustCompile(`^220 .*ESMTP Exim ([\w.]+)`),
+		product: "Exim smtpd", version: "$1"},
+	{service: "smtp", pattern: regexp.MustCompile(`^220 .*ESMTP Sendmail ([\w./]+)`),
+		product: "Sendmail", version: "$1"},
+	{service: "smtp", pattern: regexp.MustCompile(`^220 .*Microsoft ESMTP MAIL Service(?:, Version: ([\w.]+))?`),
+		product: "Microsoft ESMTP", version: "$1"},
+	{service: "smtp", pattern: regexp.MustCompile(`(?i)^220[ -].*smtp`)},
+
+	// POP3 and IMAP
+	{service: "pop3", pattern: regexp.MustCompile(`^\+OK.*Dovecot`),
+		product: "Dovecot pop3d"},
+	{service: "pop3", pattern: regexp.MustCompile(`^\+OK`)},
+	{service: "imap", pattern: regexp.MustCompile(`^\* OK.*Dovecot`),
+		product: "Dovecot imapd"},
+	{service: "imap", pattern: regexp.MustCompile(`^\* OK.*IMAP`)},
+
+	// HTTP
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: nginx(?:/([\w.]+))?`),
+		product: "nginx", version: "$1"},
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: Apache(?:/([\w.]+))?(?: \(([^)\r\n]+)\))?`),
+		product: "Apache httpd", version: "$1", extraInfo: "$2"},
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: Microsoft-IIS/([\w.]+)`),
+		product: "Microsoft IIS httpd", version: "$1"},
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: lighttpd(?:/([\w.]+))?`),
+		product: "lighttpd", version: "$1"},
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: Caddy`),
+		product: "Caddy httpd"},
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: Jetty\(([\w.-]+)\)`),
+		product: "Jetty", version: "$1"},
+	{service: "http", pattern: regexp.MustCompile(`(?is)^HTTP/1\.[01] \d{3}.*\r?\nServer: ([^\r\n/]+)(?:/([^\s\r\n]+))?`),
+		product: "$1", version: "$2"},
+	{service: "http", pattern: regexp.MustCompile(`^HTTP/1\.[01] \d{3}`)},
+
+	// Databases and caches
+	{service: "mysql", pattern: regexp.MustCompile(`(?s)^.\x00\x00\x00\x0a([\w.-]+)-MariaDB`),
+		product: "MariaDB", version: "$1"},
+	{service: "mysql", pattern: regexp.MustCompile(`(?s)^.\x00\x00\x00\x0a([\w.-]+)\x00`),
+		product: "MySQL", version: "$1"},
+	{service: "redis", pattern: regexp.MustCompile(`^(?:\+PONG|-NOAUTH|-DENIED)`),
+		product: "Redis key-value store"},
+
+	// Remote access
+	{service: "vnc", pattern: regexp.MustCompile(`^RFB (\d{3})\.(\d{3})\n`),
+		product: "VNC", extraInfo: "protocol $1.$2"},
+}
+
+// expandSignature expands template with the submatches of sig.pattern in resp as given by match.
+func expandSignature(sig *serviceSignature, resp []byte, match []int, template string) string {
+	if template == "" {
+		return ""
+	}
+	return string(sig.pattern.Expand(nil, []byte(template), resp, match))
+}
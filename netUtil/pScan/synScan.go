@@ -0,0 +1,281 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// SynScanUnsupportedError is returned if a SYN scan is not supported on the current platform.
+var SynScanUnsupportedError = errors.New("syn scan is only supported on linux")
+
+// TCP header flags
+const (
+	tcpFin = 1 << iota
+	tcpSyn
+	tcpRst
+	tcpPsh
+	tcpAck
+)
+
+// synKey identifies a probed port of a target.
+type synKey struct {
+	ip   [16]byte
+	port uint16
+}
+
+// synWaiter is waiting for the response to a single SYN or ACK probe.
+type synWaiter struct {
+	seq uint32
+	ack bool
+	ch  chan PortState
+}
+
+// synScanner sends TCP SYN packets over a raw socket and correlates the responses asynchronously with the probes
+// waiting for them. A SYN/ACK response means the port is open, a RST response means it is closed.
+// It also sends the TCP ACK packets of ACK pings, which are answered with a RST by every reachable host.
+// A single synScanner is shared by all targets of a scan.
+type synScanner struct {
+	conn    *net.IPConn
+	srcPort uint16
+
+	mu      sync.Mutex
+	waiters map[synKey][]*synWaiter
+	srcIPs  map[string]net.IP
+}
+
+// newSynScanner opens the raw socket needed for SYN scans and starts listening for responses.
+// A SynScanUnsupportedError is returned on unsupported platforms. Opening the socket requires root privileges.
+func newSynScanner() (*synScanner, error) {
+	conn, err := listenRawTCP()
+	if err != nil {
+		return nil, err
+	}
+	s := &synScanner{
+		conn:    conn,
+		srcPort: uint16(40000 + rand.Intn(20000)),
+		waiters: make(map[synKey][]*synWaiter),
+		srcIPs:  make(map[string]net.IP),
+	}
+	go s.receive()
+	return s, nil
+}
+
+// close closes the raw socket of the scanner which also stops listening for responses.
+func (s *synScanner) close() error {
+	return s.conn.Close()
+}
+
+// probe sends a SYN packet to port of dst and waits for the response. If no response is received within timeout
+// PortFiltered is returned. An error is returned if the packet couldn't be send or if ctx is done.
+func (s *synScanner) probe(ctx context.Context, dst net.IP, port uint16, timeout time.Duration) (PortState, error) {
+	return s.send(ctx, dst, port, timeout, false)
+}
+
+// ackProbe sends an ACK packet without connection to port of dst and waits for the RST response.
+// If the RST is received within timeout PortClosed is returned, otherwise PortFiltered. A response only shows that
+// the host is reachable and doesn't tell anything about the state of the port.
+// An error is returned if the packet couldn't be send or if ctx is done.
+func (s *synScanner) ackProbe(ctx context.Context, dst net.IP, port uint16, timeout time.Duration) (PortState, error) {
+	return s.send(ctx, dst, port, timeout, true)
+}
+
+// send sends either a SYN or, if ack is true, an ACK packet to port of dst and waits for the response.
+func (s *synScanner) send(ctx context.Context, dst net.IP, port uint16, timeout time.Duration, ack bool) (PortState, error) {
+	dst = dst.To4()
+	if dst == nil {
+		return PortFiltered, errors.New("syn scan only supports IPv4 targets")
+	}
+	src, err := s.sourceIP(dst)
+	if err != nil {
+		return PortFiltered, err
+	}
+
+	key := synKey{port: port}
+	copy(key.ip[:], dst.To16())
+	w := &synWaiter{seq: rand.Uint32(), ack: ack, ch: make(chan PortState, 1)}
+	s.mu.Lock()
+	s.waiters[key] = append(s.waiters[key], w)
+	s.mu.Unlock()
+	defer s.removeWaiter(key, w)
+
+	seg := buildSynSegment(src, dst, s.srcPort, port, w.seq)
+	if ack {
+		seg = buildAckSegment(src, dst, s.srcPort, port, w.seq)
+	}
+	if _, err = s.conn.WriteToIP(seg, &net.IPAddr{IP: dst}); err != nil {
+		return PortFiltered, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case state := <-w.ch:
+		return state, nil
+	case <-timer.C:
+		return PortFiltered, nil
+	case <-ctx.Done():
+		return PortFiltered, ctx.Err()
+	}
+}
+
+// receive reads all incoming TCP segments from the raw socket and hands the ones that are responses to our probes
+// over to the waiting probes until the socket is closed.
+func (s *synScanner) receive() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := s.conn.ReadFromIP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		if n < 20 || binary.BigEndian.Uint16(buf[2:4]) != s.srcPort {
+			continue
+		}
+		var state PortState
+		flags := buf[13]
+		if flags&(tcpSyn|tcpAck) == tcpSyn|tcpAck {
+			state = PortOpen
+		} else if flags&tcpRst != 0 {
+			state = PortClosed
+		} else {
+			continue
+		}
+		key := synKey{port: binary.BigEndian.Uint16(buf[0:2])}
+		copy(key.ip[:], addr.IP.To16())
+		seq := binary.BigEndian.Uint32(buf[4:8])
+		ack := binary.BigEndian.Uint32(buf[8:12])
+
+		s.mu.Lock()
+		for _, w := range s.waiters[key] {
+			// SYN probes are acknowledged, while the RST answering an ACK probe uses its acknowledgment number
+			// as sequence number.
+			if !w.ack && w.seq+1 == ack || w.ack && state == PortClosed && w.seq == seq {
+				select {
+				case w.ch <- state:
+				default:
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// removeWaiter stops the delivery of responses to w.
+func (s *synScanner) removeWaiter(key synKey, w *synWaiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws := s.waiters[key]
+	for i, o := range ws {
+		if o == w {
+			ws = append(ws[:i], ws[i+1:]...)
+			break
+		}
+	}
+	if len(ws) == 0 {
+		delete(s.waiters, key)
+	} else {
+		s.waiters[key] = ws
+	}
+}
+
+// sourceIP returns the local IP address packets to dst are sent from.
+// It is needed to calculate the checksum of the TCP segments.
+func (s *synScanner) sourceIP(dst net.IP) (net.IP, error) {
+	s.mu.Lock()
+	src, ok := s.srcIPs[dst.String()]
+	s.mu.Unlock()
+	if ok {
+		return src, nil
+	}
+	// Connecting an UDP socket doesn't send any packets but lets the kernel choose the route to dst.
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: dst, Port: 9})
+	if err != nil {
+		return nil, err
+	}
+	src = conn.LocalAddr().(*net.UDPAddr).IP.To4()
+	_ = conn.Close()
+	s.mu.Lock()
+	s.srcIPs[dst.String()] = src
+	s.mu.Unlock()
+	return src, nil
+}
+
+// buildSynSegment returns a TCP segment with the SYN flag and a MSS option set, which is send from srcPort of
+// src to dstPort of dst. The IP header is added by the kernel.
+func buildSynSegment(src, dst net.IP, srcPort, dstPort uint16, seq uint32) []byte {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint16(b[0:2], srcPort)
+	binary.BigEndian.PutUint16(b[2:4], dstPort)
+	binary.BigEndian.PutUint32(b[4:8], seq)
+	b[12] = 6 << 4 // Data offset in 32 bit words
+	b[13] = tcpSyn
+	binary.BigEndian.PutUint16(b[14:16], 1024) // Window size
+	b[20], b[21] = 2, 4                        // MSS option
+	binary.BigEndian.PutUint16(b[22:24], 1460)
+	binary.BigEndian.PutUint16(b[16:18], tcpChecksum(src, dst, b))
+	return b
+}
+
+// buildAckSegment returns a TCP segment with the ACK flag set that acknowledges ack and is send from srcPort of src
+// to dstPort of dst. The IP header is added by the kernel.
+func buildAckSegment(src, dst net.IP, srcPort, dstPort uint16, ack uint32) []byte {
+	b := make([]byte, 20)
+	binary.BigEndian.PutUint16(b[0:2], srcPort)
+	binary.BigEndian.PutUint16(b[2:4], dstPort)
+	binary.BigEndian.PutUint32(b[4:8], rand.Uint32())
+	binary.BigEndian.PutUint32(b[8:12], ack)
+	b[12] = 5 << 4 // Data offset in 32 bit words
+	b[13] = tcpAck
+	binary.BigEndian.PutUint16(b[14:16], 1024) // Window size
+	binary.BigEndian.PutUint16(b[16:18], tcpChecksum(src, dst, b))
+	return b
+}
+
+// tcpChecksum calculates the checksum of the TCP segment seg including the IPv4 pseudo header.
+func tcpChecksum(src, dst net.IP, seg []byte) uint16 {
+	var sum uint32
+	pseudo := make([]byte, 12)
+	copy(pseudo[0:4], src.To4())
+	copy(pseudo[4:8], dst.To4())
+	pseudo[9] = 6 // Protocol number of TCP
+	binary.BigEndian.PutUint16(pseudo[10:12], uint16(len(seg)))
+	for _, b := range [][]byte{pseudo, seg} {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(b[i])<<8 | uint32(b[i+1])
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestBuildSynSegment(t *testing.T) {
+	src, dst := net.ParseIP("192.0.2.1"), net.ParseIP("198.51.100.7")
+	seg := buildSynSegment(src, dst, 40000, 443, 0xdeadbeef)
+
+	if len(seg) != 24 {
+		t.Fatalf("segment length = %d, want 24", len(seg))
+	}
+	fields := []struct {
+		name      string
+		got, want uint32
+	}{
+		{"source port", uint32(binary.BigEndian.Uint16(seg[0:2])), 40000},
+		{"destination port", uint32(binary.BigEndian.Uint16(seg[2:4])), 443},
+		{"sequence number", binary.BigEndian.Uint32(seg[4:8]), 0xdeadbeef},
+		{"acknowledgment number", binary.BigEndian.Uint32(seg[8:12]), 0},
+		{"data offset", uint32(seg[12] >> 4), 6},
+		{"flags", uint32(seg[13]), tcpSyn},
+		{"window", uint32(binary.BigEndian.Uint16(seg[14:16])), 1024},
+		{"urgent pointer", uint32(binary.BigEndian.Uint16(seg[18:20])), 0},
+		{"MSS option kind", uint32(seg[20]), 2},
+		{"MSS option length", uint32(seg[21]), 4},
+		{"MSS", uint32(binary.BigEndian.Uint16(seg[22:24])), 1460},
+	}
+	for _, f := range fields {
+		if f.got != f.want {
+			t.Errorf("%s = %#x, want %#x", f.name, f.got, f.want)
+		}
+	}
+	// The checksum over a segment including its valid checksum is zero.
+	if sum := tcpChecksum(src, dst, seg); sum != 0 {
+		t.Errorf("segment doesn't verify: checksum over segment = %#x", sum)
+	}
+	// The checksum covers the pseudo header, so the same segment doesn't verify for another destination.
+	if sum := tcpChecksum(src, net.ParseIP("198.51.100.8"), seg); sum == 0 {
+		t.Error("checksum doesn't cover the destination address")
+	}
+}
+
+func TestTCPChecksum(t *testing.T) {
+	src, dst := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	tests := []struct {
+		name string
+		seg  []byte
+		want uint16
+	}{
+		// Pseudo header: 0a00 0001 0a00 0002 0006 0000 -> sum 0x1409, complement 0xebf6
+		{"empty segment", []byte{}, 0xebf6},
+		// Pseudo header with length 1 and the odd byte padded with zero: 0x1409 + 0x0001 + 0xff00 = 0x1130a
+		// -> folded 0x130b, complement 0xecf4
+		{"odd length", []byte{0xff}, 0xecf4},
+	}
+	for _, tt := range tests {
+		if got := tcpChecksum(src, dst, tt.seg); got != tt.want {
+			t.Errorf("%s: tcpChecksum = %#x, want %#x", tt.name, got, tt.want)
+		}
+	}
+}
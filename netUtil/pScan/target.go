@@ -25,7 +25,6 @@ import (
 	"fmt"
 	"github.com/ElCap1tan/gort/internal/colorFmt"
 	"github.com/ElCap1tan/gort/internal/helper"
-	"github.com/ElCap1tan/gort/internal/helper/ulimit"
 	"github.com/ElCap1tan/gort/internal/symbols"
 	"github.com/ElCap1tan/gort/netUtil"
 	"github.com/ElCap1tan/gort/netUtil/macLookup"
@@ -35,8 +34,7 @@ import (
 	"golang.org/x/sync/semaphore"
 	"net"
 	"runtime"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -65,6 +63,9 @@ const (
 
 // Target represent a network host with all the necessary fields to conduct a port scan.
 // A target always should be initialized with the NewTarget, AsyncNewTarget or ParseHostString methods.
+//
+// While a Target is scanned its Status is updated by the concurrent port scans. Use CurrentStatus to read the
+// status during a scan, for example from the OnPort callback of ScanOptions.
 type Target struct {
 	// HostName is string containing the host name of the Target.
 	HostName HostName
@@ -92,24 +93,127 @@ type Target struct {
 
 	// RTTs contains the round trip times of the ping requests if they could be send successfully.
 	RTTs []time.Duration
+
+	// statusMu guards Status while the Target is scanned.
+	statusMu sync.Mutex
+
+	// excluded is true if the Target is part of the ExcludeList of the host discovery.
+	excluded bool
+
+	// probed is true if at least one discovery probe was sent to the Target.
+	probed bool
+
+	// macRequested is true if an ARP-request or NDP neighbor solicitation was already sent to the Target
+	// during the host discovery.
+	macRequested bool
+}
+
+// DiscoveryOptions contains the optional settings of the host discovery performed when targets are created.
+// A nil pointer to DiscoveryOptions is valid and results in a host discovery with the default settings.
+type DiscoveryOptions struct {
+	// Privileged controls if the host discovery should be run either in a (more detailed) mode that requires
+	// root privileges, or (in the less detailed) 'user' mode.
+	Privileged bool
+
+	// Concurrency is the maximum number of targets that are resolved simultaneously.
+	// If Concurrency is zero the limit is the maximum number of open files allowed by the system.
+	Concurrency int
+
+	// RateLimiter limits the rate of the ping and ARP requests. It can be shared with ScanOptions to limit
+	// the overall rate of the scan. If RateLimiter is nil the rate isn't limited.
+	RateLimiter *RateLimiter
+
+	// Probes are the probes sent to every target to determine if it is up. They are sent in their order until one
+	// of them is answered. Defaults to ICMPEchoProbe.
+	Probes []DiscoveryProbe
+
+	// SynPorts are the ports TCPSynProbe is sent to. Defaults to DefaultDiscoveryPorts.
+	SynPorts []uint16
+
+	// AckPorts are the ports TCPAckProbe is sent to. Defaults to DefaultDiscoveryPorts.
+	AckPorts []uint16
+
+	// ConnectPorts are the ports TCPConnectProbe connects to. Defaults to DefaultDiscoveryPorts.
+	ConnectPorts []uint16
+
+	// SkipDiscovery disables the discovery probes. The status of the targets stays unknown until a port responds
+	// and no target is considered down.
+	SkipDiscovery bool
+
+	// PingCount is the number of ICMP echo requests sent to every target. Defaults to 3.
+	PingCount int
+
+	// PingTimeout is the time to wait for the replies to a discovery probe. Defaults to 3 seconds.
+	PingTimeout time.Duration
+
+	// VendorResolver looks up the vendors of the targets with a known MAC-address. If VendorResolver is nil,
+	// macLookup.LookupVendor is used.
+	VendorResolver macLookup.VendorResolver
+
+	// Exclude contains the hosts that must never be touched. Excluded targets are recognized before any traffic
+	// is sent to them and are neither resolved nor pinged or scanned. If Exclude is nil no host is excluded.
+	Exclude *ExcludeList
+
+	// env contains the resources shared by the discovery of all targets. It is created by the functions that
+	// perform the discovery if it is nil.
+	env *discoveryEnv
+}
+
+// withDefaults returns a copy of the DiscoveryOptions pointer, which may be nil, with all unset fields set
+// to their default values.
+func (o *DiscoveryOptions) withDefaults() *DiscoveryOptions {
+	opts := DiscoveryOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.PingCount <= 0 {
+		opts.PingCount = 3
+	}
+	if opts.PingTimeout <= 0 {
+		opts.PingTimeout = 3000 * time.Millisecond
+	}
+	if len(opts.Probes) == 0 {
+		opts.Probes = []DiscoveryProbe{ICMPEchoProbe}
+	}
+	if len(opts.SynPorts) == 0 {
+		opts.SynPorts = DefaultDiscoveryPorts
+	}
+	if len(opts.AckPorts) == 0 {
+		opts.AckPorts = DefaultDiscoveryPorts
+	}
+	if len(opts.ConnectPorts) == 0 {
+		opts.ConnectPorts = DefaultDiscoveryPorts
+	}
+	return &opts
 }
 
 // NewTarget returns a pointer to an initialized instance of Target as defined
 // by the targetAddress and ports. Before returning the Target, it is resolved by calling Target.Resolve.
-// If the resolve was successful, AsyncNewTarget will try to send a ping request by calling Target.Ping and to query
-// the MAC-address and vendor name  by calling Target.QueryMac and Target.LookUpVendor. scanLock is used to controls
-// how many targets may be resolved simultaneously and privileged controls if the scan should be run either
-// in a (more detailed) mode that require root privileges, or (in the less detailed) 'user' mode.
-func NewTarget(targetAddress string, ports netUtil.Ports, privileged bool) *Target {
+// If the resolve was successful, NewTarget will send the discovery probes of opts to determine if the Target is up
+// and try to query the MAC-address and vendor name  by calling Target.QueryMac and Target.LookUpVendor with the
+// VendorResolver of opts. The MAC-address isn't queried if the host discovery is skipped or already sent an ARP or
+// NDP probe to the Target. opts controls the optional settings of the host discovery and can be nil.
+// If ctx is done before all steps are finished, the remaining steps are skipped. Targets excluded by opts.Exclude are
+// marked as excluded and returned as soon as the exclusion is recognized.
+func NewTarget(ctx context.Context, targetAddress string, ports netUtil.Ports, opts *DiscoveryOptions) *Target {
+	opts = opts.withDefaults()
+	if opts.env == nil {
+		opts.env = newDiscoveryEnv()
+		defer opts.env.close()
+	}
 	h := &Target{InitialTarget: targetAddress, Ports: ports, Status: Unknown}
-	h.Resolve()
+	if h.exclude(opts.Exclude) {
+		return h
+	}
+	if h.resolve(ctx, opts.Exclude) {
+		return h
+	}
 	if h.IPAddr != nil {
-		stats, _ := h.Ping(3, privileged)
-		if stats.PacketsRecv > 0 {
-			h.Status = Online
+		h.discover(ctx, opts)
+		if h.needsMacQuery(opts) {
+			h.queryMac(ctx, opts.RateLimiter)
 		}
-		h.QueryMac()
-		h.LookUpVendor()
+		h.lookUpVendor(opts.VendorResolver)
 	} else {
 		h.MACAddr = nil
 		h.Location = UnknownLoc
@@ -119,229 +223,399 @@ func NewTarget(targetAddress string, ports netUtil.Ports, privileged bool) *Targ
 
 // AsyncNewTarget asynchronously creates a pointer to an initialized instance of Target as defined
 // by the targetAddress and ports. Before returning the Target over ch, it is resolved by calling Target.Resolve.
-// If the resolve was successful, AsyncNewTarget will try to send a ping request by calling Target.Ping and to query
-// the MAC-address and vendor name  by calling Target.QueryMac and Target.LookUpVendor. scanLock is used to controls
-// how many targets may be resolved simultaneously and privileged controls if the scan should be run either
-// in a (more detailed) mode that require root privileges, or (in the less detailed) 'user' mode.
-func AsyncNewTarget(targetAddress string, ports netUtil.Ports, ch chan *Target, scanLock *semaphore.Weighted, privileged bool) {
-	// TODO Add writeMutex
+// If the resolve was successful, AsyncNewTarget will send the discovery probes of opts to determine if the Target is
+// up and try to query the MAC-address and vendor name  by calling Target.QueryMac and Target.LookUpVendor with the
+// VendorResolver of opts. As with NewTarget, the MAC-address isn't queried if the host discovery is skipped or already
+// sent an ARP or NDP probe. scanLock is used to controls how many targets may be resolved simultaneously and opts
+// controls the optional settings of the host discovery and can be nil.
+// If ctx is done before all steps are finished, the remaining steps are skipped. Targets excluded by opts.Exclude are
+// marked as excluded and sent as soon as the exclusion is recognized. The Target is always sent over ch.
+func AsyncNewTarget(ctx context.Context, targetAddress string, ports netUtil.Ports, ch chan *Target,
+	scanLock *semaphore.Weighted, opts *DiscoveryOptions) {
+	opts = opts.withDefaults()
+	if opts.env == nil {
+		opts.env = newDiscoveryEnv()
+		defer opts.env.close()
+	}
 	h := &Target{InitialTarget: targetAddress, Ports: ports, Status: Unknown}
-	scanLock.Acquire(context.TODO(), 1)
-	h.Resolve()
+	defer func() { ch <- h }()
+	if scanLock.Acquire(ctx, 1) != nil {
+		h.Location = UnknownLoc
+		return
+	}
+	if h.exclude(opts.Exclude) {
+		scanLock.Release(1)
+		return
+	}
+	excluded := h.resolve(ctx, opts.Exclude)
 	scanLock.Release(1)
+	if excluded {
+		return
+	}
 	if h.IPAddr != nil {
-		scanLock.Acquire(context.TODO(), 1)
-		stats, _ := h.Ping(3, privileged)
-		scanLock.Release(1)
-		if stats.PacketsRecv > 0 {
-			h.Status = Online
+		if scanLock.Acquire(ctx, 1) != nil {
+			return
 		}
-		scanLock.Acquire(context.TODO(), 1)
-		h.QueryMac()
+		h.discover(ctx, opts)
 		scanLock.Release(1)
-		scanLock.Acquire(context.TODO(), 1)
-		h.LookUpVendor()
+		if h.needsMacQuery(opts) {
+			if scanLock.Acquire(ctx, 1) != nil {
+				return
+			}
+			h.queryMac(ctx, opts.RateLimiter)
+			scanLock.Release(1)
+		}
+		if scanLock.Acquire(ctx, 1) != nil {
+			return
+		}
+		h.lookUpVendor(opts.VendorResolver)
 		scanLock.Release(1)
 	} else {
 		h.MACAddr = nil
 		h.Location = UnknownLoc
 	}
-	ch <- h
 }
 
-// ParseHostString parses hosts and returns the initialized Targets.
+// MaxIPv6RangeSize is the maximum number of addresses an IPv6 CIDR or range passed to ParseHostString
+// may contain. Larger IPv6 networks are skipped, as they can't be scanned by expanding every single address anyway.
+var MaxIPv6RangeSize uint64 = 65536
+
+// ParseHostString parses hosts and returns the initialized Targets. All addresses are resolved before ParseHostString
+// returns, so huge ranges should rather be resolved and scanned as a pipeline with a TargetIterator.
 //
 // hosts is comma separated list of values that can be in either of the following formats:
-// - A single IP address: 192.88.99.1
-// - A range of IP addresses: 192.88.99-100.1-100
-// - A CIDR formatted IP address range: 192.88.99.1/24
+// - A single IP address: 192.88.99.1 or 2001:db8::1
+// - A range of IP addresses: 192.88.99-100.1-100 or 2001:db8::1-ff
+// - A CIDR formatted IP address range: 192.88.99.1/24 or 2001:db8::/120
 // - A host name: example.com
 //
+// IPv6 CIDRs and ranges with more than MaxIPv6RangeSize addresses are skipped.
+//
 // ports is a list of type ports that should be scanned for every host in hosts.
 //
-// privileged controls if the targets should be resolved either in
-// a (more detailed) mode that require root privileges or (in the less detailed) 'user' mode.
-func ParseHostString(hosts string, ports netUtil.Ports, privileged bool) Targets {
+// opts controls the optional settings of the host discovery and can be nil.
+// If ctx is done while the targets are resolved, the targets that are being resolved are returned as unresolved and
+// the remaining addresses are skipped.
+func ParseHostString(ctx context.Context, hosts string, ports netUtil.Ports, opts *DiscoveryOptions) Targets {
 	var tgtHosts Targets
-	hostCount := 0
-	out := make(chan *Target)
-
-	var limit int64
-	l, err := ulimit.GetUlimit()
-	if err != nil {
-		limit = 1024
-	} else {
-		limit = int64(l)
-	}
-
-	lock := semaphore.NewWeighted(limit)
-
-	hostList := strings.Split(hosts, ",")
-	for _, hostArg := range hostList {
-		if ip, ipNet, err := net.ParseCIDR(hostArg); err == nil {
-			for ip := ip.Mask(ipNet.Mask); ipNet.Contains(ip); helper.IncIp(ip) {
-				go AsyncNewTarget(ip.String(), ports, out, lock, privileged)
-				hostCount++
-			}
-		} else if helper.ValidateIPOrRange(hostArg) {
-			if strings.Contains(hostArg, "-") {
-				addrParts := strings.Split(hostArg, ".")
-				var octets [4][]int
-				for i, addrPart := range addrParts {
-					if strings.Contains(addrPart, "-") {
-						octets[i] = append(octets[i], helper.StrRangeToArray(addrPart)...)
-					} else {
-						p, _ := strconv.Atoi(addrPart)
-						octets[i] = append(octets[i], p)
-					}
-				}
-				for _, t := range octetsToTargets(octets) {
-					go AsyncNewTarget(t, ports, out, lock, privileged)
-					hostCount++
-				}
-			} else {
-				go AsyncNewTarget(hostArg, ports, out, lock, privileged)
-				hostCount++
-			}
-		} else {
-			go AsyncNewTarget(hostArg, ports, out, lock, privileged)
-			hostCount++
-		}
-	}
-	for i := 1; i <= hostCount; i++ {
-		tgtHosts = append(tgtHosts, <-out)
+	for t := range NewTargetIterator(hosts, false).Resolve(ctx, ports, opts) {
+		tgtHosts = append(tgtHosts, t)
 	}
 	return tgtHosts
 }
 
 // Resolve tries to resolve the IP address and the host name of the Target pointer.
-func (t *Target) Resolve() {
+// Host names are resolved to their IPv4 address if they have an A record and to their IPv6 address otherwise.
+// The lookups are aborted when ctx is done.
+func (t *Target) Resolve(ctx context.Context) {
+	t.resolve(ctx, nil)
+}
+
+// resolve works like Resolve but checks the resolved IP address of host names against exclude, which can be nil,
+// before its host name is looked up. If the Target is excluded, it is marked as such, the lookup is skipped and true
+// is returned.
+func (t *Target) resolve(ctx context.Context, exclude *ExcludeList) bool {
 	if helper.ValidateIPOrRange(t.InitialTarget) {
-		hostNames, err := net.LookupAddr(t.InitialTarget)
-		if err != nil {
+		t.IPAddr = net.ParseIP(t.InitialTarget)
+		if t.exclude(exclude) {
+			return true
+		}
+		hostNames, err := net.DefaultResolver.LookupAddr(ctx, t.InitialTarget)
+		if err != nil || len(hostNames) == 0 {
 			t.HostName = "N/A"
 		} else {
 			t.HostName = HostName(hostNames[0])
 		}
-		t.IPAddr = net.ParseIP(t.InitialTarget)
 	} else {
-		ips, err := net.LookupIP(t.InitialTarget)
-		if err == nil {
-			t.IPAddr = ips[0]
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, t.InitialTarget)
+		if err == nil && len(ips) > 0 {
+			t.IPAddr = ips[0].IP
+			for _, ip := range ips {
+				if ip.IP.To4() != nil {
+					t.IPAddr = ip.IP
+					break
+				}
+			}
+			if t.exclude(exclude) {
+				return true
+			}
 
-			hostNames, err := net.LookupAddr(string(t.IPAddr))
-			if err == nil {
+			hostNames, err := net.DefaultResolver.LookupAddr(ctx, t.IPAddr.String())
+			if err == nil && len(hostNames) > 0 {
 				t.HostName = HostName(hostNames[0])
 			} else {
 				t.HostName = HostName(t.InitialTarget)
 			}
 		} else {
 			t.IPAddr = nil
-			t.Status = OfflineFiltered
+			t.updateStatus(OfflineFiltered)
 		}
 	}
+	return false
 }
 
 // QueryMac tries to query the MAC address of the Target pointer either by ARP cache lookup or alternatively if
-// not found in cache by sending an ARP-request.
-func (t *Target) QueryMac() {
+// not found in cache by sending an ARP-request. For IPv6 targets the neighbor cache and NDP neighbor solicitations
+// are used instead. The request is aborted when ctx is done.
+func (t *Target) QueryMac(ctx context.Context) {
+	t.queryMac(ctx, nil)
+}
+
+// queryMac works like QueryMac but waits for limiter, which may be nil, before sending a request.
+func (t *Target) queryMac(ctx context.Context, limiter *RateLimiter) {
 	if b, err := t.IsHost(); b == true && err == nil {
 		return
 	}
-	if macAddr, err := net.ParseMAC(quickArp.Search(t.IPAddr.String())); err == nil && macAddr.String() != "00:00:00:00:00:00" {
+	if ctx.Err() != nil {
+		t.MACAddr = nil
+		return
+	}
+	if t.IPAddr.To4() == nil {
+		if macAddr := searchNeighborCache(t.IPAddr); macAddr != nil {
+			colorFmt.Infof("%s Found MAC address for target '%s' via neighbor cache lookup: %s\n",
+				symbols.INFO, t.InitialTarget, macAddr.String())
+			t.Location = Local
+			t.MACAddr = macAddr
+			return
+		}
+	} else if macAddr, err := net.ParseMAC(quickArp.Search(t.IPAddr.String())); err == nil && macAddr.String() != "00:00:00:00:00:00" {
 		colorFmt.Infof("%s Found MAC address for target '%s' via arp cache lookup: %s\n",
 			symbols.INFO, t.InitialTarget, macAddr.String())
 		t.Location = Local
 		t.MACAddr = macAddr
 		return
 	}
-	// Fallback if not found in arp cache
-	interfaces, err := net.Interfaces()
+	// Fallback if not found in cache
+	_ = t.requestMac(ctx, limiter)
+}
+
+// requestMac tries to query the MAC address of the Target pointer by sending an ARP-request or for IPv6 targets a NDP
+// neighbor solicitation if the Target is part of a local network. The NetworkLocation of the Target is updated
+// accordingly. limiter, which may be nil, is waited for before sending the request.
+// An error is returned if the request couldn't be sent, e.g. because of missing privileges.
+func (t *Target) requestMac(ctx context.Context, limiter *RateLimiter) error {
+	inf, err := localInterface(t.IPAddr)
 	if err != nil {
 		t.Location = UnknownLoc
 		t.MACAddr = nil
-		return
+		return err
 	}
-	for _, inf := range interfaces {
+	if inf == nil {
+		t.Location = Global
+		t.MACAddr = nil
+		return nil
+	}
+	t.Location = Local
+	if err = limiter.Wait(ctx); err != nil {
+		t.MACAddr = nil
+		return err
+	} else if t.IPAddr.To4() == nil {
+		return t.ndpQueryMac(ctx, inf)
+	}
+	return t.arpQueryMac(ctx, inf)
+}
+
+// localInterface returns the network interface whose network contains ip or nil if ip isn't part of a local network.
+func localInterface(ip net.IP) (*net.Interface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i, inf := range interfaces {
 		infAddresses, err := inf.Addrs()
 		if err != nil {
 			continue
 		}
 		for _, addr := range infAddresses {
-			if _, ipNet, err := net.ParseCIDR(addr.String()); err == nil {
-				if ipNet.Contains(t.IPAddr) {
-					t.Location = Local
-					if arpCli, err := arp.Dial(&inf); err == nil {
-						err = arpCli.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
-						if err != nil {
-							colorFmt.Warnf("%s %s: Error setting read timeout for arp request. Skipping mac lookup...\n",
-								symbols.INFO, t.IPAddr.String())
-							t.MACAddr = nil
-							_ = arpCli.Close()
-							return
-						}
-						hwAddr, err := arpCli.Resolve(t.IPAddr)
-						if err != nil || hwAddr.String() == "00:00:00:00:00:00" {
-							t.MACAddr = nil
-							_ = arpCli.Close()
-							return
-						}
-						colorFmt.Infof("%s Found MAC address for target '%s' via arp request: %s\n",
-							symbols.INFO, t.InitialTarget, hwAddr.String())
-						t.MACAddr = hwAddr
-						t.Status = Online
-						_ = arpCli.Close()
-						return
-					} else {
-						t.MACAddr = nil
-						return
-					}
-				}
+			if _, ipNet, err := net.ParseCIDR(addr.String()); err == nil && ipNet.Contains(ip) {
+				return &interfaces[i], nil
 			}
 		}
 	}
-	t.Location = Global
-	t.MACAddr = nil
-	return
+	return nil, nil
+}
+
+// arpQueryMac tries to query the MAC address of the Target pointer by sending an ARP-request over inf.
+// The ARP-request is aborted when ctx is done. An error is only returned if the ARP client couldn't be created.
+func (t *Target) arpQueryMac(ctx context.Context, inf *net.Interface) error {
+	arpCli, err := arp.Dial(inf)
+	if err != nil {
+		t.MACAddr = nil
+		return err
+	}
+	defer arpCli.Close()
+	deadline := time.Now().Add(500 * time.Millisecond)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	err = arpCli.SetDeadline(deadline)
+	if err != nil {
+		colorFmt.Warnf("%s %s: Error setting read timeout for arp request. Skipping mac lookup...\n",
+			symbols.INFO, t.IPAddr.String())
+		t.MACAddr = nil
+		return nil
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Unblocks the pending request
+			_ = arpCli.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	hwAddr, err := arpCli.Resolve(t.IPAddr)
+	close(stop)
+	if err != nil || hwAddr.String() == "00:00:00:00:00:00" {
+		t.MACAddr = nil
+		return nil
+	}
+	colorFmt.Infof("%s Found MAC address for target '%s' via arp request: %s\n",
+		symbols.INFO, t.InitialTarget, hwAddr.String())
+	t.MACAddr = hwAddr
+	t.updateStatus(Online)
+	return nil
+}
+
+// ndpQueryMac tries to query the MAC address of the Target pointer by sending a NDP neighbor solicitation over inf.
+// The request is aborted when ctx is done. An error is returned if the solicitation couldn't be sent.
+func (t *Target) ndpQueryMac(ctx context.Context, inf *net.Interface) error {
+	hwAddr, err := ndpResolve(ctx, inf, t.IPAddr, 500*time.Millisecond)
+	if err != nil || hwAddr.String() == "00:00:00:00:00:00" {
+		t.MACAddr = nil
+		if nErr, ok := err.(net.Error); ok && nErr.Timeout() || ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	colorFmt.Infof("%s Found MAC address for target '%s' via neighbor solicitation: %s\n",
+		symbols.INFO, t.InitialTarget, hwAddr.String())
+	t.MACAddr = hwAddr
+	t.updateStatus(Online)
+	return nil
 }
 
-// LookUpVendor tries to perform a vendor lookup based on the MAC address of the Target pointer by sending
-// a HTTP request to the vendor lookup API of 'macvendors.co'.
+// LookUpVendor tries to perform a vendor lookup based on the MAC address of the Target pointer by calling
+// macLookup.LookupVendor, which answers from the resolver configured with macLookup.UseVendorDB or
+// macLookup.SetDefaultResolver. If none is configured, the vendor stays unknown.
 func (t *Target) LookUpVendor() {
+	t.lookUpVendor(nil)
+}
+
+// lookUpVendor works like LookUpVendor but looks up the vendor with resolver if it isn't nil.
+func (t *Target) lookUpVendor(resolver macLookup.VendorResolver) {
 	if t.MACAddr != nil {
-		vendorRes, err := macLookup.LookupVendor(t.MACAddr)
-		if err != nil {
-			t.Vendor = "N/A"
+		if resolver == nil {
+			if vendorRes, err := macLookup.LookupVendor(t.MACAddr); err == nil {
+				t.Vendor = vendorRes.Company
+				return
+			}
+		} else if vendor, err := resolver.ResolveVendor(t.MACAddr); err == nil {
+			t.Vendor = vendor.Company
 			return
 		}
-		t.Vendor = vendorRes.Company
-		return
 	}
 	t.Vendor = "N/A"
 }
 
 // Ping sends a ping request to the IP address of the Target pointer. count specifies how many requests should be send
-// and privileged
-func (t *Target) Ping(count int, privileged bool) (*ping.Statistics, error) {
+// and privileged controls if the requests are send via raw sockets. The requests are aborted at the deadline of ctx.
+// If ctx is done before all replies are received, Ping returns ctx.Err() once the pending requests timed out.
+func (t *Target) Ping(ctx context.Context, count int, privileged bool) (*ping.Statistics, error) {
+	return t.ping(ctx, &DiscoveryOptions{PingCount: count, Privileged: privileged, PingTimeout: 3000 * time.Millisecond})
+}
+
+// ping sends a ping request to the IP address of the Target pointer with the count, timeout and privilege
+// settings of opts. Before the pinger is started, it waits for the RateLimiter of opts for every request.
+// The requests are aborted at the deadline of ctx. If ctx is done before all replies are received, ping returns
+// ctx.Err() once the pending requests timed out.
+func (t *Target) ping(ctx context.Context, opts *DiscoveryOptions) (*ping.Statistics, error) {
+	for i := 0; i < opts.PingCount; i++ {
+		if err := opts.RateLimiter.Wait(ctx); err != nil {
+			t.RTTs = nil
+			return nil, err
+		}
+	}
 	pinger, err := ping.NewPinger(t.IPAddr.String())
 	if err != nil {
 		t.RTTs = nil
 		return nil, err
 	}
-	pinger.Timeout = time.Millisecond * 3000
-	pinger.Count = count
+	pinger.Timeout = opts.PingTimeout
+	if d, ok := ctx.Deadline(); ok && time.Until(d) < pinger.Timeout {
+		pinger.Timeout = time.Until(d)
+	}
+	if pinger.Timeout <= 0 {
+		t.RTTs = nil
+		return nil, context.DeadlineExceeded
+	}
+	pinger.Count = opts.PingCount
 
-	pinger.SetPrivileged(privileged || runtime.GOOS == "windows")
+	pinger.SetPrivileged(opts.Privileged || runtime.GOOS == "windows")
 
+	// A running pinger can't be stopped safely, as it closes its done channel itself. Instead, its timeout is
+	// bounded by the deadline of ctx and Run is always waited for.
 	pinger.Run()
+	if err := ctx.Err(); err != nil {
+		t.RTTs = nil
+		return nil, err
+	}
 
 	stats := pinger.Statistics()
 	t.RTTs = stats.Rtts
 	return stats, nil
 }
 
+// Excluded returns true if the Target is part of the ExcludeList of the host discovery it was created with.
+// Excluded targets aren't scanned.
+func (t *Target) Excluded() bool {
+	return t.excluded
+}
+
+// exclude marks the Target pointer as excluded if its initial address or its IP address are contained in e and
+// returns whether it is excluded.
+func (t *Target) exclude(e *ExcludeList) bool {
+	if !t.excluded && e.excludesTarget(t) {
+		t.excluded = true
+		colorFmt.Infof("%s Skipping excluded target '%s'...\n", symbols.INFO, t.InitialTarget)
+	}
+	return t.excluded
+}
+
+// CurrentStatus returns the TargetStatus of the Target pointer.
+// Other than reading Status directly it is safe to call while the Target is scanned.
+func (t *Target) CurrentStatus() TargetStatus {
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+	return t.Status
+}
+
+// Down returns true if the discovery probes were sent to the Target pointer and it didn't respond to any of them.
+// Targets whose discovery was skipped are never down.
+func (t *Target) Down() bool {
+	return t.probed && t.CurrentStatus() != Online
+}
+
+// updateStatus records ts as the Status of the Target pointer. It is safe to call while the Target is scanned.
+// Concurrent probes of the Target report their outcome in any order, so the transitions are:
+//   - Online always wins, as a single response proves that the Target is up.
+//   - OfflineFiltered only replaces Unknown, so it never hides a response received before.
+//   - Unknown never overwrites a known status.
+func (t *Target) updateStatus(ts TargetStatus) {
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+	switch ts {
+	case Online:
+		t.Status = Online
+	case OfflineFiltered:
+		if t.Status == Unknown {
+			t.Status = OfflineFiltered
+		}
+	}
+}
+
 // AvgRTT calculates the average RTT of the last Target.Ping call.
-func (t Target) AvgRTT() time.Duration {
+func (t *Target) AvgRTT() time.Duration {
 	if len(t.RTTs) == 0 {
 		return -1
 	}
@@ -377,7 +651,7 @@ func (t *Target) IsHost() (bool, error) {
 					return true, nil
 				}
 			case *net.IPNet:
-				ip = net.ParseIP(strings.Split(v.IP.String(), ":")[0])
+				ip = v.IP
 				if t.IPAddr.Equal(ip) {
 					t.Location = Local
 					t.MACAddr = inf.HardwareAddr
@@ -413,7 +687,7 @@ func (t *Target) String() string {
 		t.Vendor,
 		mac,
 		t.Location,
-		t.Status,
+		t.CurrentStatus(),
 		t.Ports.Preview(30))
 }
 
@@ -462,7 +736,7 @@ func (t *Target) ColorString() string {
 		vendor,
 		mac,
 		t.Location.ColorString(),
-		t.Status.ColorString(),
+		t.CurrentStatus().ColorString(),
 		t.Ports.Preview(30))
 }
 
@@ -490,6 +764,26 @@ func (n NetworkLocation) String() string {
 	return "N/A"
 }
 
+// id returns a short machine readable identifier of TargetStatus used by the structured output formats.
+func (ts TargetStatus) id() string {
+	if ts == Online {
+		return "online"
+	} else if ts == OfflineFiltered {
+		return "offline-filtered"
+	}
+	return "unknown"
+}
+
+// id returns a short machine readable identifier of NetworkLocation used by the structured output formats.
+func (n NetworkLocation) id() string {
+	if n == Local {
+		return "local"
+	} else if n == Global {
+		return "global"
+	}
+	return "unknown"
+}
+
 // ColorString returns a colored string representation of TargetStatus.
 func (ts TargetStatus) ColorString() string {
 	if ts == Online {
@@ -514,18 +808,25 @@ func (n NetworkLocation) ColorString() string {
 	return "N/A"
 }
 
-// octetsToTargets takes a two-dimensional array containing a list of values for each of the four octets as values
-// and returns an string array containing all the possible IP combinations you can build up with it.
-func octetsToTargets(octets [4][]int) []string {
-	var targets []string
-	for _, oc0 := range octets[0] {
-		for _, oc1 := range octets[1] {
-			for _, oc2 := range octets[2] {
-				for _, oc3 := range octets[3] {
-					targets = append(targets, fmt.Sprintf("%d.%d.%d.%d", oc0, oc1, oc2, oc3))
-				}
-			}
+// rangeSize returns the number of addresses described by octets as returned by helper.IPRangeSegments.
+// The returned value saturates at MaxIPv6RangeSize + 1 to prevent overflows.
+func rangeSize(octets [][]int) uint64 {
+	size := uint64(1)
+	for _, oc := range octets {
+		size *= uint64(len(oc))
+		if size > MaxIPv6RangeSize {
+			return MaxIPv6RangeSize + 1
 		}
 	}
-	return targets
+	return size
+}
+
+// cidrSize returns the number of addresses in ipNet.
+// The returned value saturates at MaxIPv6RangeSize + 1 to prevent overflows.
+func cidrSize(ipNet *net.IPNet) uint64 {
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones >= 64 || uint64(1)<<uint(bits-ones) > MaxIPv6RangeSize {
+		return MaxIPv6RangeSize + 1
+	}
+	return uint64(1) << uint(bits-ones)
 }
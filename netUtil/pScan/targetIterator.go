@@ -0,0 +1,318 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"bufio"
+	"context"
+	"github.com/ElCap1tan/gort/internal/colorFmt"
+	"github.com/ElCap1tan/gort/internal/helper"
+	"github.com/ElCap1tan/gort/internal/helper/ulimit"
+	"github.com/ElCap1tan/gort/internal/symbols"
+	"github.com/ElCap1tan/gort/netUtil"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// TargetIterator lazily generates the addresses described by a list of host specifications, so even huge CIDR
+// ranges can be scanned without holding all of their addresses in memory. The addresses are either generated in
+// the order of the specifications or, if randomized, in a random order over all specifications, which spreads the
+// load of a scan over all subnets.
+// A TargetIterator always should be created with NewTargetIterator. It isn't safe for concurrent use.
+type TargetIterator struct {
+	specs []hostSpec
+	size  uint64
+
+	// pos is the index of the next address if the addresses are generated in order.
+	pos uint64
+
+	// perm generates the indices of the addresses if they are generated in random order and is nil otherwise.
+	perm *permutation
+}
+
+// hostSpec is a single host specification whose addresses are generated by index.
+type hostSpec interface {
+	// size returns the number of addresses of the hostSpec.
+	size() uint64
+
+	// at returns the address with index i, which must be smaller than size.
+	at(i uint64) string
+}
+
+// singleSpec is a host specification consisting of a single IP address or host name.
+type singleSpec string
+
+// cidrSpec is a host specification consisting of all addresses of a CIDR formatted IP address range.
+type cidrSpec struct {
+	base  net.IP
+	count uint64
+}
+
+// rangeSpec is a host specification consisting of the combinations of the values of every segment of
+// an IP address as returned by helper.IPRangeSegments.
+type rangeSpec [][]int
+
+// NewTargetIterator returns a pointer to a new TargetIterator for hosts, which is a comma separated list of host
+// specifications in the formats accepted by ParseHostString. If random is true, the addresses are generated
+// in random order.
+//
+// IPv6 CIDRs and ranges with more than MaxIPv6RangeSize addresses are skipped.
+func NewTargetIterator(hosts string, random bool) *TargetIterator {
+	return NewTargetIteratorFromSpecs(strings.Split(hosts, ","), random)
+}
+
+// NewTargetIteratorFromSpecs returns a pointer to a new TargetIterator for specs, where every element is a single
+// host specification in the formats accepted by ParseHostString, as returned by ReadHostSpecs for example.
+// If random is true, the addresses are generated in random order.
+//
+// IPv6 CIDRs and ranges with more than MaxIPv6RangeSize addresses are skipped.
+func NewTargetIteratorFromSpecs(specs []string, random bool) *TargetIterator {
+	it := &TargetIterator{}
+	for _, hostArg := range specs {
+		var spec hostSpec
+		if ip, ipNet, err := net.ParseCIDR(hostArg); err == nil {
+			if ip.To4() == nil && cidrSize(ipNet) > MaxIPv6RangeSize {
+				colorFmt.Warnf("%s Skipping '%s' because it contains more than %d addresses...\n",
+					symbols.INFO, hostArg, MaxIPv6RangeSize)
+				continue
+			}
+			ones, bits := ipNet.Mask.Size()
+			spec = cidrSpec{base: ip.Mask(ipNet.Mask), count: uint64(1) << uint(bits-ones)}
+		} else if octets, ok := helper.IPRangeSegments(hostArg); ok && strings.Contains(hostArg, "-") {
+			if len(octets) == 8 && rangeSize(octets) > MaxIPv6RangeSize {
+				colorFmt.Warnf("%s Skipping '%s' because it contains more than %d addresses...\n",
+					symbols.INFO, hostArg, MaxIPv6RangeSize)
+				continue
+			}
+			spec = rangeSpec(octets)
+		} else {
+			spec = singleSpec(hostArg)
+		}
+		it.specs = append(it.specs, spec)
+		it.size += spec.size()
+	}
+	if random {
+		it.perm = newPermutation(it.size, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+	return it
+}
+
+// Len returns the total number of addresses generated by the TargetIterator.
+func (it *TargetIterator) Len() uint64 {
+	return it.size
+}
+
+// Next returns the next address of the TargetIterator. The second return value is false if all addresses
+// were generated already.
+func (it *TargetIterator) Next() (string, bool) {
+	var i uint64
+	if it.perm != nil {
+		var ok bool
+		if i, ok = it.perm.next(); !ok {
+			return "", false
+		}
+	} else {
+		if it.pos >= it.size {
+			return "", false
+		}
+		i = it.pos
+		it.pos++
+	}
+	for _, spec := range it.specs {
+		if i < spec.size() {
+			return spec.at(i), true
+		}
+		i -= spec.size()
+	}
+	return "", false
+}
+
+// Resolve starts the host discovery for the addresses of the TargetIterator and returns a channel over which
+// every Target is sent as soon as it is created by AsyncNewTarget. The addresses are only generated as fast as the
+// targets are received, so Resolve can feed a scan started with ScanTargetStream as a pipeline.
+// ports is a list of ports that should be scanned for every target and opts controls the optional settings of
+// the host discovery and can be nil. At most opts.Concurrency targets are resolved simultaneously.
+// Addresses and targets excluded by opts.Exclude are skipped and not sent.
+// The channel is closed once all targets are sent. If ctx is done, the targets that are being resolved are sent
+// as unresolved and the remaining addresses are skipped.
+func (it *TargetIterator) Resolve(ctx context.Context, ports netUtil.Ports, opts *DiscoveryOptions) <-chan *Target {
+	out := make(chan *Target)
+	opts = opts.withDefaults()
+	opts.env = newDiscoveryEnv()
+	var limit int64
+	l, err := ulimit.GetUlimit()
+	if err != nil {
+		limit = 1024
+	} else {
+		limit = int64(l)
+	}
+	if opts.Concurrency > 0 && int64(opts.Concurrency) < limit {
+		limit = int64(opts.Concurrency)
+	}
+
+	lock := semaphore.NewWeighted(limit)
+	// pending limits the number of targets that are resolved or waiting to be received,
+	// so the memory use doesn't depend on the number of addresses.
+	pending := semaphore.NewWeighted(limit)
+	go func() {
+		var wg sync.WaitGroup
+		for addr, ok := it.Next(); ok && pending.Acquire(ctx, 1) == nil; addr, ok = it.Next() {
+			if opts.Exclude.Excludes(addr) {
+				pending.Release(1)
+				continue
+			}
+			wg.Add(1)
+			go func(addr string) {
+				defer wg.Done()
+				defer pending.Release(1)
+				ch := make(chan *Target, 1)
+				AsyncNewTarget(ctx, addr, ports, ch, lock, opts)
+				// Host names can resolve to an excluded address
+				if t := <-ch; !t.Excluded() {
+					out <- t
+				}
+			}(addr)
+		}
+		wg.Wait()
+		opts.env.close()
+		close(out)
+	}()
+	return out
+}
+
+// ReadHostSpecs reads the host specifications in r. Every line contains a single host specification or a comma
+// separated list of them in the formats accepted by ParseHostString. Blank lines and everything after a # are ignored.
+func ReadHostSpecs(r io.Reader) ([]string, error) {
+	var specs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, spec := range strings.Split(line, ",") {
+			if spec = strings.TrimSpace(spec); spec != "" {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	return specs, scanner.Err()
+}
+
+// size returns the number of addresses of the singleSpec, which is always one.
+func (s singleSpec) size() uint64 {
+	return 1
+}
+
+// at returns the address of the singleSpec.
+func (s singleSpec) at(uint64) string {
+	return string(s)
+}
+
+// size returns the number of addresses in the CIDR range.
+func (s cidrSpec) size() uint64 {
+	return s.count
+}
+
+// at returns the address with index i of the CIDR range, which is the base address of the range plus i.
+func (s cidrSpec) at(i uint64) string {
+	ip := make(net.IP, len(s.base))
+	copy(ip, s.base)
+	for j := len(ip) - 1; j >= 0 && i > 0; j-- {
+		sum := uint64(ip[j]) + i&0xff
+		ip[j] = byte(sum)
+		i = i>>8 + sum>>8
+	}
+	return ip.String()
+}
+
+// size returns the number of addresses in the range.
+func (s rangeSpec) size() uint64 {
+	size := uint64(1)
+	for _, seg := range s {
+		size *= uint64(len(seg))
+	}
+	return size
+}
+
+// at returns the address with index i of the range. The index is split up into the value indices of the segments
+// with the last segment changing the fastest.
+func (s rangeSpec) at(i uint64) string {
+	ip := make(net.IP, 16)
+	if len(s) == 4 {
+		copy(ip, net.IPv4zero.To16())
+	}
+	for j := len(s) - 1; j >= 0; j-- {
+		seg := s[j]
+		v := seg[i%uint64(len(seg))]
+		i /= uint64(len(seg))
+		if len(s) == 4 {
+			ip[12+j] = byte(v)
+		} else {
+			ip[2*j], ip[2*j+1] = byte(v>>8), byte(v)
+		}
+	}
+	return ip.String()
+}
+
+// permutation generates a pseudo random permutation of the numbers in [0, n) without storing them.
+// It uses a linear congruential generator with a full period over the smallest power of two m >= n and
+// skips the values >= n (cycle walking), so at most 2n steps are needed to generate all numbers.
+type permutation struct {
+	n, mask uint64
+	a, c, x uint64
+	emitted uint64
+}
+
+// newPermutation returns a pointer to a new permutation of [0, n) with parameters chosen by rnd.
+func newPermutation(n uint64, rnd *rand.Rand) *permutation {
+	m := uint64(1)
+	for m < n {
+		m <<= 1
+	}
+	// By the Hull-Dobell theorem the generator has a full period of m if c is odd and a-1 is divisible by four.
+	return &permutation{
+		n:    n,
+		mask: m - 1,
+		a:    rnd.Uint64()&^3 | 1,
+		c:    rnd.Uint64() | 1,
+		x:    rnd.Uint64(),
+	}
+}
+
+// next returns the next number of the permutation. The second return value is false if all numbers
+// were generated already.
+func (p *permutation) next() (uint64, bool) {
+	for p.emitted < p.n {
+		p.x = (p.a*p.x + p.c) & p.mask
+		if p.x < p.n {
+			p.emitted++
+			return p.x, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collect returns all addresses generated by it and fails the test if an address is generated twice.
+func collect(t *testing.T, it *TargetIterator) map[string]bool {
+	t.Helper()
+	seen := make(map[string]bool)
+	for addr, ok := it.Next(); ok; addr, ok = it.Next() {
+		if seen[addr] {
+			t.Fatalf("address %s was generated twice", addr)
+		}
+		seen[addr] = true
+	}
+	return seen
+}
+
+func TestTargetIteratorInOrder(t *testing.T) {
+	it := NewTargetIterator("192.168.0.0/30,10.0.1-2.5,example.com,2001:db8::1-2", false)
+	want := []string{
+		"192.168.0.0", "192.168.0.1", "192.168.0.2", "192.168.0.3",
+		"10.0.1.5", "10.0.2.5",
+		"example.com",
+		"2001:db8::1", "2001:db8::2",
+	}
+	if it.Len() != uint64(len(want)) {
+		t.Fatalf("Len() = %d, want %d", it.Len(), len(want))
+	}
+	var got []string
+	for addr, ok := it.Next(); ok; addr, ok = it.Next() {
+		got = append(got, addr)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("Next() returned an address after the last one")
+	}
+}
+
+func TestTargetIteratorRandomized(t *testing.T) {
+	hosts := "10.0.0.0/22,172.16.1-3.1-100,2001:db8::/120"
+	want := collect(t, NewTargetIterator(hosts, false))
+	if len(want) != 1024+300+256 {
+		t.Fatalf("got %d addresses in order, want %d", len(want), 1024+300+256)
+	}
+	it := NewTargetIterator(hosts, true)
+	got := collect(t, it)
+	if len(got) != len(want) {
+		t.Fatalf("got %d randomized addresses, want %d", len(got), len(want))
+	}
+	for addr := range want {
+		if !got[addr] {
+			t.Errorf("randomized iterator didn't generate %s", addr)
+		}
+	}
+}
+
+func TestTargetIteratorSkipsHugeIPv6Ranges(t *testing.T) {
+	it := NewTargetIterator("2001:db8::/64,127.0.0.1", false)
+	if it.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", it.Len())
+	}
+	if addr, ok := it.Next(); !ok || addr != "127.0.0.1" {
+		t.Errorf("Next() = %s, %t, want 127.0.0.1, true", addr, ok)
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []uint64{0, 1, 2, 3, 7, 8, 9, 100, 1000, 4097} {
+		for round := 0; round < 5; round++ {
+			p := newPermutation(n, rnd)
+			seen := make([]bool, n)
+			var count uint64
+			for i, ok := p.next(); ok; i, ok = p.next() {
+				if i >= n {
+					t.Fatalf("n=%d: generated %d out of range", n, i)
+				}
+				if seen[i] {
+					t.Fatalf("n=%d: generated %d twice", n, i)
+				}
+				seen[i] = true
+				count++
+			}
+			if count != n {
+				t.Fatalf("n=%d: generated %d numbers, want %d", n, count, n)
+			}
+			if _, ok := p.next(); ok {
+				t.Fatalf("n=%d: next() returned a number after the last one", n)
+			}
+		}
+	}
+}
+
+func TestReadHostSpecs(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     []string
+	}{
+		{"empty", "", nil},
+		{"one per line", "10.0.0.1\nexample.com\n2001:db8::1", []string{"10.0.0.1", "example.com", "2001:db8::1"}},
+		{"comma separated", "10.0.0.1, example.com,,10.0.0.0/24\n", []string{"10.0.0.1", "example.com", "10.0.0.0/24"}},
+		{"comments and blank lines", "10.0.0.1 # gateway\n\n   \n# only a comment\n10.0.1-2.5\n",
+			[]string{"10.0.0.1", "10.0.1-2.5"}},
+		{"windows line endings", "10.0.0.1\r\nexample.com\r\n", []string{"10.0.0.1", "example.com"}},
+	}
+	for _, tt := range tests {
+		got, err := ReadHostSpecs(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadHostSpecsError(t *testing.T) {
+	want := errors.New("read failed")
+	if _, err := ReadHostSpecs(io.MultiReader(strings.NewReader("10.0.0.1\n"), errReader{want})); err != want {
+		t.Errorf("error = %v, want %v", err, want)
+	}
+}
+
+// errReader is an io.Reader that always fails with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
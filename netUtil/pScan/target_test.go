@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	tests := []struct {
+		from, to, want TargetStatus
+	}{
+		{Unknown, Online, Online},
+		{Unknown, OfflineFiltered, OfflineFiltered},
+		{Unknown, Unknown, Unknown},
+		{OfflineFiltered, Online, Online},
+		{OfflineFiltered, OfflineFiltered, OfflineFiltered},
+		{OfflineFiltered, Unknown, OfflineFiltered},
+		{Online, OfflineFiltered, Online},
+		{Online, Unknown, Online},
+		{Online, Online, Online},
+	}
+	for _, tt := range tests {
+		target := &Target{Status: tt.from}
+		target.updateStatus(tt.to)
+		if target.Status != tt.want {
+			t.Errorf("updateStatus(%s) on %s = %s, want %s", tt.to, tt.from, target.Status, tt.want)
+		}
+	}
+}
+
+func TestUpdateStatusConcurrent(t *testing.T) {
+	target := &Target{Status: Unknown}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 50 {
+				target.updateStatus(Online)
+			} else {
+				target.updateStatus(OfflineFiltered)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if target.Status != Online {
+		t.Errorf("status = %s, want %s", target.Status, Online)
+	}
+}
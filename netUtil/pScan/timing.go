@@ -0,0 +1,179 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var UnknownTimingTemplateError = errors.New("unknown timing template")
+
+// RateLimiter limits the rate of probes sent during a scan. A single RateLimiter can be shared by the
+// host discovery and the port scan of all targets to limit the overall rate.
+// A nil pointer to RateLimiter is valid and doesn't limit the rate at all.
+type RateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimiter returns a pointer to a new RateLimiter that allows rate probes per second.
+// If rate is zero or negative nil is returned, which doesn't limit the rate.
+func NewRateLimiter(rate float64) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Wait blocks until the next probe may be sent. It returns ctx.Err() if ctx is done before.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	slot := r.next
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	d := time.Until(slot)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TimingTemplate is an integer representing a named set of timing settings for the host discovery and port scan.
+// The values range from the slowest and least noisy TimingParanoid to the fastest TimingInsane.
+type TimingTemplate int
+
+const (
+	TimingParanoid TimingTemplate = iota
+	TimingSneaky
+	TimingPolite
+	TimingNormal
+	TimingAggressive
+	TimingInsane
+)
+
+// timingSettings contains the settings bundled by a TimingTemplate.
+type timingSettings struct {
+	concurrency int
+	maxHosts    int
+	rate        float64
+	timeout     time.Duration
+	retries     int
+	pingCount   int
+	pingTimeout time.Duration
+}
+
+// timingTemplates contains the settings of every TimingTemplate. A zero concurrency or maxHosts means that
+// the limit is only given by the maximum number of open files and a zero rate means that the rate isn't limited.
+var timingTemplates = map[TimingTemplate]timingSettings{
+	TimingParanoid:   {concurrency: 1, maxHosts: 1, rate: 0.2, timeout: 5 * time.Second, retries: 2, pingCount: 1, pingTimeout: 5 * time.Second},
+	TimingSneaky:     {concurrency: 1, maxHosts: 1, rate: 2, timeout: 5 * time.Second, retries: 2, pingCount: 1, pingTimeout: 5 * time.Second},
+	TimingPolite:     {concurrency: 10, maxHosts: 4, rate: 20, timeout: 3 * time.Second, retries: 1, pingCount: 2, pingTimeout: 3 * time.Second},
+	TimingNormal:     {timeout: 3 * time.Second, pingCount: 3, pingTimeout: 3 * time.Second},
+	TimingAggressive: {timeout: 1250 * time.Millisecond, pingCount: 2, pingTimeout: 1250 * time.Millisecond},
+	TimingInsane:     {timeout: 300 * time.Millisecond, pingCount: 1, pingTimeout: 500 * time.Millisecond},
+}
+
+// ParseTimingTemplate returns the TimingTemplate with the name or number s (e.g. polite or 2).
+func ParseTimingTemplate(s string) (TimingTemplate, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if _, ok := timingTemplates[TimingTemplate(n)]; ok {
+			return TimingTemplate(n), nil
+		}
+		return TimingNormal, UnknownTimingTemplateError
+	}
+	for tt := range timingTemplates {
+		if strings.EqualFold(tt.String(), s) {
+			return tt, nil
+		}
+	}
+	return TimingNormal, UnknownTimingTemplateError
+}
+
+// ScanOptions returns a pointer to new ScanOptions with the port scan settings of the TimingTemplate.
+// The RateLimiter of the options is limiter, so that it can be shared with the host discovery.
+func (tt TimingTemplate) ScanOptions(limiter *RateLimiter) *ScanOptions {
+	s := timingTemplates[tt]
+	return &ScanOptions{
+		Concurrency: s.concurrency,
+		MaxHosts:    s.maxHosts,
+		RateLimiter: limiter,
+		Timeout:     s.timeout,
+		Retries:     s.retries,
+	}
+}
+
+// DiscoveryOptions returns a pointer to new DiscoveryOptions with the host discovery settings of the TimingTemplate.
+// The RateLimiter of the options is limiter, so that it can be shared with the port scan.
+func (tt TimingTemplate) DiscoveryOptions(limiter *RateLimiter) *DiscoveryOptions {
+	s := timingTemplates[tt]
+	return &DiscoveryOptions{
+		Concurrency: s.concurrency,
+		RateLimiter: limiter,
+		PingCount:   s.pingCount,
+		PingTimeout: s.pingTimeout,
+	}
+}
+
+// RateLimiter returns a pointer to a new RateLimiter with the rate of the TimingTemplate.
+func (tt TimingTemplate) RateLimiter() *RateLimiter {
+	return NewRateLimiter(timingTemplates[tt].rate)
+}
+
+// String returns a string representation of TimingTemplate.
+func (tt TimingTemplate) String() string {
+	switch tt {
+	case TimingParanoid:
+		return "paranoid"
+	case TimingSneaky:
+		return "sneaky"
+	case TimingPolite:
+		return "polite"
+	case TimingNormal:
+		return "normal"
+	case TimingAggressive:
+		return "aggressive"
+	case TimingInsane:
+		return "insane"
+	}
+	return "N/A"
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want time.Duration
+	}{
+		{0, 0},
+		{-1, 0},
+		{1, time.Second},
+		{100, 10 * time.Millisecond},
+		{0.5, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		r := NewRateLimiter(tt.rate)
+		if tt.want == 0 {
+			if r != nil {
+				t.Errorf("NewRateLimiter(%v) = %+v, want nil", tt.rate, r)
+			}
+			continue
+		}
+		if r == nil || r.interval != tt.want {
+			t.Errorf("NewRateLimiter(%v) = %+v, want interval %v", tt.rate, r, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	r := NewRateLimiter(100)
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		if err := r.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first probe is sent immediately and the following ten every 10ms.
+	if elapsed := time.Since(start); elapsed < 95*time.Millisecond {
+		t.Errorf("11 probes at 100/s took %v, want at least 100ms", elapsed)
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var nilLimiter *RateLimiter
+	if err := nilLimiter.Wait(ctx); err != context.Canceled {
+		t.Errorf("nil RateLimiter.Wait() = %v, want %v", err, context.Canceled)
+	}
+	r := NewRateLimiter(1)
+	_ = r.Wait(context.Background())
+	start := time.Now()
+	if err := r.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Wait() on a canceled context took %v", elapsed)
+	}
+}
+
+func TestParseTimingTemplate(t *testing.T) {
+	tests := []struct {
+		s    string
+		want TimingTemplate
+		err  error
+	}{
+		{"0", TimingParanoid, nil},
+		{"paranoid", TimingParanoid, nil},
+		{"Polite", TimingPolite, nil},
+		{"3", TimingNormal, nil},
+		{"aggressive", TimingAggressive, nil},
+		{"5", TimingInsane, nil},
+		{"6", TimingNormal, UnknownTimingTemplateError},
+		{"-1", TimingNormal, UnknownTimingTemplateError},
+		{"fast", TimingNormal, UnknownTimingTemplateError},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimingTemplate(tt.s)
+		if got != tt.want || err != tt.err {
+			t.Errorf("ParseTimingTemplate(%q) = %v, %v, want %v, %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestTimingTemplateOptions(t *testing.T) {
+	for tt := TimingParanoid; tt <= TimingInsane; tt++ {
+		s := timingTemplates[tt]
+		limiter := tt.RateLimiter()
+		if (s.rate == 0) != (limiter == nil) {
+			t.Errorf("%v: RateLimiter() = %+v for rate %v", tt, limiter, s.rate)
+		}
+		scanOpts := tt.ScanOptions(limiter)
+		if scanOpts.Timeout != s.timeout || scanOpts.Retries != s.retries || scanOpts.Concurrency != s.concurrency ||
+			scanOpts.MaxHosts != s.maxHosts || scanOpts.RateLimiter != limiter {
+			t.Errorf("%v: ScanOptions() = %+v", tt, scanOpts)
+		}
+		discOpts := tt.DiscoveryOptions(limiter)
+		if discOpts.PingCount != s.pingCount || discOpts.PingTimeout != s.pingTimeout ||
+			discOpts.Concurrency != s.concurrency || discOpts.RateLimiter != limiter {
+			t.Errorf("%v: DiscoveryOptions() = %+v", tt, discOpts)
+		}
+	}
+	// Slower templates never use shorter timeouts than faster ones.
+	for tt := TimingParanoid; tt < TimingInsane; tt++ {
+		if timingTemplates[tt].timeout < timingTemplates[tt+1].timeout {
+			t.Errorf("%v has a shorter timeout than %v", tt, tt+1)
+		}
+	}
+}
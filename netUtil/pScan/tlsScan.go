@@ -0,0 +1,214 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/ElCap1tan/gort/netUtil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tlsHandshakeTimeout is the minimum time the dial and the TLS handshake with an open port may take together.
+// It is independent of the port timeout, which only covers the connect and can be far shorter than a handshake.
+const tlsHandshakeTimeout = 5 * time.Second
+
+// DefaultCertExpiryWarning is the default time before the expiry of a certificate it is flagged as expiring soon.
+const DefaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// TLSInfo contains the information gathered by a TLS handshake with an open port.
+type TLSInfo struct {
+	// Version is the negotiated TLS version (e.g. TLS 1.3).
+	Version string
+
+	// CipherSuite is the name of the negotiated cipher suite.
+	CipherSuite string
+
+	// Subject is the distinguished name of the subject of the server certificate.
+	Subject string
+
+	// SANs contains the DNS names, IP addresses, email addresses and URIs of the subject alternative name
+	// extension of the server certificate.
+	SANs []string
+
+	// Issuer is the distinguished name of the issuer of the server certificate.
+	Issuer string
+
+	// NotBefore and NotAfter are the bounds of the validity period of the server certificate.
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	// KeyType is the type of the public key of the server certificate (RSA, ECDSA or Ed25519)
+	// and KeyBits its size in bits.
+	KeyType string
+	KeyBits int
+
+	// SelfSigned is true if the server certificate is signed by its own key.
+	SelfSigned bool
+
+	// Trusted is true if the certificate chain sent by the server could be verified against the system roots.
+	// Neither the host name nor the validity period are part of the verification.
+	Trusted bool
+
+	// Expired is true if the server certificate wasn't valid at the time of the scan.
+	Expired bool
+
+	// ExpiresSoon is true if the server certificate is valid but expires within the warning period of the scan.
+	ExpiresSoon bool
+}
+
+// String returns a string representation of the TLSInfo pointer.
+func (ti *TLSInfo) String() string {
+	ret := fmt.Sprintf("%s, %s\n", ti.Version, ti.CipherSuite)
+	ret += fmt.Sprintf("Subject: %s", ti.Subject)
+	if len(ti.SANs) > 0 {
+		ret += fmt.Sprintf(" | SANs: %s", strings.Join(ti.SANs, ", "))
+	}
+	ret += fmt.Sprintf("\nIssuer: %s\n", ti.Issuer)
+	ret += fmt.Sprintf("Valid: %s - %s | Key: %s %d bit",
+		ti.NotBefore.Format("2006-01-02"), ti.NotAfter.Format("2006-01-02"), ti.KeyType, ti.KeyBits)
+	if flags := ti.flags(); len(flags) > 0 {
+		ret += " | " + strings.Join(flags, ", ")
+	}
+	return ret
+}
+
+// flags returns the warnings about the certificate of the TLSInfo pointer.
+func (ti *TLSInfo) flags() []string {
+	var flags []string
+	if ti.Expired {
+		flags = append(flags, "EXPIRED")
+	} else if ti.ExpiresSoon {
+		flags = append(flags, fmt.Sprintf("EXPIRES IN %d DAYS", int(time.Until(ti.NotAfter).Hours()/24)))
+	}
+	if ti.SelfSigned {
+		flags = append(flags, "SELF-SIGNED")
+	} else if !ti.Trusted {
+		flags = append(flags, "UNTRUSTED")
+	}
+	return flags
+}
+
+// inspectTLS tries to complete a TLS handshake with port p of the Target and returns the negotiated parameters
+// and the details of the server certificate. Certificates expiring within expiryWarning are flagged.
+// nil is returned if the port doesn't speak TLS or the handshake doesn't finish within timeout.
+func (t *Target) inspectTLS(ctx context.Context, p *netUtil.Port, timeout, expiryWarning time.Duration) *TLSInfo {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(t.IPAddr.String(), strconv.Itoa(int(p.PortNo))))
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	if d, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(d)
+	}
+
+	config := &tls.Config{InsecureSkipVerify: true}
+	if t.HostName != "" && t.HostName != "N/A" && net.ParseIP(t.InitialTarget) == nil {
+		config.ServerName = t.InitialTarget
+	}
+	tlsConn := tls.Client(conn, config)
+	if err = tlsConn.Handshake(); err != nil {
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return newTLSInfo(state, time.Now(), expiryWarning)
+}
+
+// newTLSInfo returns a pointer to a new TLSInfo describing the connection state. now is the time the validity of
+// the certificate is checked at and expiryWarning the time before the expiry the certificate is flagged.
+func newTLSInfo(state tls.ConnectionState, now time.Time, expiryWarning time.Duration) *TLSInfo {
+	cert := state.PeerCertificates[0]
+	ti := &TLSInfo{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Expired:     now.Before(cert.NotBefore) || now.After(cert.NotAfter),
+	}
+	ti.ExpiresSoon = !ti.Expired && now.Add(expiryWarning).After(cert.NotAfter)
+	ti.SANs = append(ti.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		ti.SANs = append(ti.SANs, ip.String())
+	}
+	ti.SANs = append(ti.SANs, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		ti.SANs = append(ti.SANs, uri.String())
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		ti.KeyType, ti.KeyBits = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		ti.KeyType, ti.KeyBits = "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		ti.KeyType, ti.KeyBits = "Ed25519", 256
+	default:
+		ti.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+	ti.SelfSigned = cert.Subject.String() == cert.Issuer.String() && cert.CheckSignatureFrom(cert) == nil
+
+	// An expired certificate would never verify, so the chain is verified within its validity period.
+	verifyTime := now
+	if now.Before(cert.NotBefore) {
+		verifyTime = cert.NotBefore
+	} else if now.After(cert.NotAfter) {
+		verifyTime = cert.NotAfter
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: verifyTime})
+	ti.Trusted = err == nil
+	return ti
+}
+
+// tlsVersionName returns the name of the TLS version v.
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionSSL30:
+		return "SSL 3.0"
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
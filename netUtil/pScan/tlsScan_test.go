@@ -0,0 +1,180 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/ElCap1tan/gort/netUtil"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a self-signed ECDSA certificate for localhost and 127.0.0.1 that is valid from
+// notBefore until notAfter.
+func newTestCertificate(t *testing.T, notBefore, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestNewTLSInfo(t *testing.T) {
+	now := time.Date(2020, time.October, 11, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		notBefore, notAfter  time.Time
+		expired, expiresSoon bool
+	}{
+		{"valid", now.AddDate(0, -1, 0), now.AddDate(1, 0, 0), false, false},
+		{"expires soon", now.AddDate(0, -1, 0), now.AddDate(0, 0, 10), false, true},
+		{"expired", now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1), true, false},
+		{"not yet valid", now.AddDate(0, 0, 1), now.AddDate(1, 0, 0), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := x509.ParseCertificate(newTestCertificate(t, tt.notBefore, tt.notAfter).Certificate[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := tls.ConnectionState{
+				Version:          tls.VersionTLS12,
+				CipherSuite:      tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				PeerCertificates: []*x509.Certificate{cert},
+			}
+			ti := newTLSInfo(state, now, 30*24*time.Hour)
+			want := &TLSInfo{
+				Version:     "TLS 1.2",
+				CipherSuite: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+				Subject:     "CN=localhost",
+				SANs:        []string{"localhost", "127.0.0.1"},
+				Issuer:      "CN=localhost",
+				NotBefore:   cert.NotBefore,
+				NotAfter:    cert.NotAfter,
+				KeyType:     "ECDSA",
+				KeyBits:     256,
+				SelfSigned:  true,
+				Expired:     tt.expired,
+				ExpiresSoon: tt.expiresSoon,
+			}
+			if !reflect.DeepEqual(ti, want) {
+				t.Errorf("newTLSInfo() = %+v, want %+v", ti, want)
+			}
+		})
+	}
+}
+
+func TestTLSInfoFlags(t *testing.T) {
+	tests := []struct {
+		ti   TLSInfo
+		want []string
+	}{
+		{TLSInfo{Trusted: true}, nil},
+		{TLSInfo{}, []string{"UNTRUSTED"}},
+		{TLSInfo{SelfSigned: true, Expired: true}, []string{"EXPIRED", "SELF-SIGNED"}},
+	}
+	for _, tt := range tests {
+		if got := tt.ti.flags(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("flags() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestTLSVersionName(t *testing.T) {
+	tests := []struct {
+		v    uint16
+		want string
+	}{
+		{tls.VersionTLS10, "TLS 1.0"},
+		{tls.VersionTLS12, "TLS 1.2"},
+		{tls.VersionTLS13, "TLS 1.3"},
+		{0x7f1c, "0x7f1c"},
+	}
+	for _, tt := range tests {
+		if got := tlsVersionName(tt.v); got != tt.want {
+			t.Errorf("tlsVersionName(%#x) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestInspectTLS(t *testing.T) {
+	cert := newTestCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	plain := serveTCPLoopback(t, func(conn net.Conn) { _, _ = conn.Read(make([]byte, 512)) })
+
+	target := &Target{InitialTarget: "127.0.0.1", IPAddr: net.IPv4(127, 0, 0, 1)}
+	port := func(l net.Listener) *netUtil.Port {
+		return netUtil.NewPort(uint16(l.Addr().(*net.TCPAddr).Port), "tcp", "N/A", "No description available")
+	}
+	ti := target.inspectTLS(context.Background(), port(l), time.Second, 30*24*time.Hour)
+	if ti == nil {
+		t.Fatal("inspectTLS() = nil for a TLS port")
+	}
+	if ti.Subject != "CN=localhost" || !ti.SelfSigned || ti.Expired || !ti.ExpiresSoon {
+		t.Errorf("inspectTLS() = %+v", ti)
+	}
+	// The plain port never answers the client hello, so the handshake times out.
+	if ti := target.inspectTLS(context.Background(), port(plain), 200*time.Millisecond, 0); ti != nil {
+		t.Errorf("inspectTLS() = %+v for a plain TCP port, want nil", ti)
+	}
+}
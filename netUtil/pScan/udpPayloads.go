@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+// udpPayloads contains protocol specific payloads for well known UDP ports that are likely to elicit a response
+// from the service running on the port. Services on ports without a payload are probed with an empty datagram.
+var udpPayloads = map[uint16][]byte{
+	// DNS: Query for the TXT record version.bind of class CHAOS
+	53: []byte("\x00\x06\x01\x00\x00\x01\x00\x00\x00\x00\x00\x00" +
+		"\x07version\x04bind\x00\x00\x10\x00\x03"),
+	// TFTP: Read request for a file in octet mode
+	69: []byte("\x00\x01gort.txt\x00octet\x00"),
+	// NTP: Version 4 client request
+	123: append([]byte{0xe3}, make([]byte, 47)...),
+	// NetBIOS: Node status request for the wildcard name
+	137: []byte("\x80\xf0\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00" +
+		"\x20CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\x00\x00\x21\x00\x01"),
+	// SNMP: Version 1 get request for sysDescr.0 with the community public
+	161: []byte("\x30\x29\x02\x01\x00\x04\x06public" +
+		"\xa0\x1c\x02\x04\x00\x00\x00\x01\x02\x01\x00\x02\x01\x00" +
+		"\x30\x0e\x30\x0c\x06\x08\x2b\x06\x01\x02\x01\x01\x01\x00\x05\x00"),
+	// IPMI: RMCP presence ping
+	623: []byte("\x06\x00\xff\x06\x00\x00\x11\xbe\x80\x00\x00\x00"),
+	// SSDP: Discovery request
+	1900: []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n"),
+	// SIP: OPTIONS request
+	5060: []byte("OPTIONS sip:nm SIP/2.0\r\nVia: SIP/2.0/UDP nm;branch=foo\r\nFrom: <sip:nm@nm>;tag=root\r\n" +
+		"To: <sip:nm2@nm2>\r\nCall-ID: 50000\r\nCSeq: 42 OPTIONS\r\nMax-Forwards: 70\r\nContent-Length: 0\r\n\r\n"),
+	// mDNS: Query for all advertised services
+	5353: []byte("\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00" +
+		"\x09_services\x07_dns-sd\x04_udp\x05local\x00\x00\x0c\x00\x01"),
+	// Memcached: stats command with UDP frame header
+	11211: []byte("\x00\x01\x00\x00\x00\x01\x00\x00stats\r\n"),
+}
+
+// udpPayload returns the payload UDP probes for port are sent with.
+func udpPayload(port uint16) []byte {
+	if payload, ok := udpPayloads[port]; ok {
+		return payload
+	}
+	return []byte{}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// udpKey identifies a single UDP probe by the target address, the target port and the local source port.
+type udpKey struct {
+	ip      [16]byte
+	port    uint16
+	srcPort uint16
+}
+
+// udpScanner sends UDP probes and waits for a response. A port that responds is open.
+// A port is closed when an ICMP port unreachable message is received. This is either reported by the kernel as
+// refused connection on the connected socket of the probe or, if the process is privileged enough to open a raw
+// ICMP socket, by the ICMP listener of the udpScanner that also recognizes the unreachable messages of firewalls.
+// If there is no response at all, the port is open or filtered.
+// A single udpScanner is shared by all targets of a scan.
+type udpScanner struct {
+	icmp *icmp.PacketConn
+
+	mu      sync.Mutex
+	waiters map[udpKey]chan PortReason
+}
+
+// newUDPScanner returns a pointer to a new udpScanner and starts the ICMP listener if possible.
+func newUDPScanner() *udpScanner {
+	u := &udpScanner{waiters: make(map[udpKey]chan PortReason)}
+	if conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0"); err == nil {
+		u.icmp = conn
+		go u.receive()
+	}
+	return u
+}
+
+// close stops the ICMP listener of the scanner.
+func (u *udpScanner) close() error {
+	if u.icmp != nil {
+		return u.icmp.Close()
+	}
+	return nil
+}
+
+// probe sends a UDP datagram with a payload suitable for the service commonly found on port to dst and waits for
+// the response. If no response is received within timeout, ReasonNoResponse is returned.
+// An error is returned together with its classification if the datagram couldn't be send or if ctx is done.
+func (u *udpScanner) probe(ctx context.Context, dst net.IP, port uint16, timeout time.Duration) (PortReason, error) {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: dst, Port: int(port)})
+	if err != nil {
+		return classifyError(err), err
+	}
+	defer conn.Close()
+
+	key := udpKey{port: port, srcPort: uint16(conn.LocalAddr().(*net.UDPAddr).Port)}
+	copy(key.ip[:], dst.To16())
+	unreachable := make(chan PortReason, 1)
+	u.mu.Lock()
+	u.waiters[key] = unreachable
+	u.mu.Unlock()
+	defer func() {
+		u.mu.Lock()
+		delete(u.waiters, key)
+		u.mu.Unlock()
+	}()
+
+	if _, err = conn.Write(udpPayload(port)); err != nil {
+		if isPortUnreachable(err) {
+			return ReasonPortUnreachable, nil
+		}
+		return classifyError(err), err
+	}
+
+	read := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		_, err := conn.Read(buf)
+		read <- err
+	}()
+	select {
+	case err = <-read:
+		if err == nil {
+			return ReasonUDPResponse, nil
+		} else if isPortUnreachable(err) {
+			return ReasonPortUnreachable, nil
+		} else if reason := classifyError(err); reason != ReasonNoResponse {
+			return reason, err
+		}
+		return ReasonNoResponse, nil
+	case reason := <-unreachable:
+		return reason, nil
+	case <-ctx.Done():
+		return ReasonCanceled, ctx.Err()
+	}
+}
+
+// receive reads all incoming ICMP messages and hands destination unreachable messages for UDP probes over to the
+// waiting probes until the listener is closed.
+func (u *udpScanner) receive() {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := u.icmp.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		msg, err := icmp.ParseMessage(1, buf[:n])
+		if err != nil || msg.Type != ipv4.ICMPTypeDestinationUnreachable {
+			continue
+		}
+		var reason PortReason
+		switch msg.Code {
+		case 0:
+			reason = ReasonNetUnreachable
+		case 1:
+			reason = ReasonHostUnreachable
+		case 2:
+			reason = ReasonProtoUnreachable
+		case 3:
+			reason = ReasonPortUnreachable
+		case 9, 10, 13: // Communication administratively prohibited
+			reason = ReasonAdminProhibited
+		default:
+			continue
+		}
+		body, ok := msg.Body.(*icmp.DstUnreach)
+		if !ok {
+			continue
+		}
+		// The body contains the IP header and at least the first 8 bytes of the original datagram.
+		data := body.Data
+		if len(data) < 20 || data[9] != 17 {
+			continue
+		}
+		ihl := int(data[0]&0x0f) * 4
+		if len(data) < ihl+4 {
+			continue
+		}
+		key := udpKey{
+			port:    uint16(data[ihl+2])<<8 | uint16(data[ihl+3]),
+			srcPort: uint16(data[ihl])<<8 | uint16(data[ihl+1]),
+		}
+		copy(key.ip[:], net.IP(data[16:20]).To16())
+
+		u.mu.Lock()
+		if ch, ok := u.waiters[key]; ok {
+			select {
+			case ch <- reason:
+			default:
+			}
+		}
+		u.mu.Unlock()
+	}
+}
+
+// isPortUnreachable returns true if err reports an ICMP port unreachable message received on a connected UDP socket.
+// Unix systems report it as refused connection and Windows as reset connection.
+func isPortUnreachable(err error) bool {
+	reason := classifyError(err)
+	return reason == ReasonRefused || reason == ReasonConnReset
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// listenUDPLoopback starts a UDP listener on the loopback interface that answers every datagram if echo is true
+// and returns its port.
+func listenUDPLoopback(t *testing.T, echo bool) uint16 {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if echo {
+				_, _ = conn.WriteToUDP(buf[:n], addr)
+			}
+		}
+	}()
+	return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// closedUDPPort returns a loopback UDP port that is not in use.
+func closedUDPPort(t *testing.T) uint16 {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+	_ = conn.Close()
+	return port
+}
+
+func TestUDPProbe(t *testing.T) {
+	// The ICMP listener isn't started, so closed ports are only detected through the connected socket.
+	u := &udpScanner{waiters: make(map[udpKey]chan PortReason)}
+	tests := []struct {
+		name string
+		port uint16
+		want PortReason
+	}{
+		{"response", listenUDPLoopback(t, true), ReasonUDPResponse},
+		{"no response", listenUDPLoopback(t, false), ReasonNoResponse},
+		{"port unreachable", closedUDPPort(t), ReasonPortUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.probe(context.Background(), net.IPv4(127, 0, 0, 1), tt.port, 200*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("probe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUDPProbeCanceled(t *testing.T) {
+	u := &udpScanner{waiters: make(map[udpKey]chan PortReason)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got, err := u.probe(ctx, net.IPv4(127, 0, 0, 1), listenUDPLoopback(t, false), time.Second)
+	if err != context.Canceled {
+		t.Errorf("probe() error = %v, want %v", err, context.Canceled)
+	}
+	if got != ReasonCanceled {
+		t.Errorf("probe() = %v, want %v", got, ReasonCanceled)
+	}
+}
+
+func TestIsPortUnreachable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&net.OpError{Op: "read", Net: "udp", Err: timeoutError{}}, false},
+		{errors.New("connection refused"), false},
+	}
+	// Unix systems report the ICMP message as refused and Windows as reset connection.
+	for errno, reason := range errnoReasons {
+		tests = append(tests, struct {
+			err  error
+			want bool
+		}{&net.OpError{Op: "read", Net: "udp", Err: os.NewSyscallError("recvfrom", errno)},
+			reason == ReasonRefused || reason == ReasonConnReset})
+	}
+	for _, tt := range tests {
+		if got := isPortUnreachable(tt.err); got != tt.want {
+			t.Errorf("isPortUnreachable(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
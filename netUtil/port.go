@@ -28,8 +28,15 @@ import (
 	"github.com/ElCap1tan/gort/internal/xmlParser"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// portRegistries caches the loaded PortRegistry of every data folder, so that the registry is only loaded once.
+var portRegistries = struct {
+	sync.Mutex
+	byFolder map[string]*xmlParser.PortRegistry
+}{byFolder: make(map[string]*xmlParser.PortRegistry)}
+
 // Ports is a list of Port pointers.
 type Ports []*Port
 
@@ -70,95 +77,52 @@ func NewPort(portNo uint16, proto string, service string, desc string) *Port {
 // be found under https://www.iana.org/assignments/service-names-port-numbers/service-names-port-numbers.xml).
 func ParsePortString(ports string, proto string, dataFolder string) Ports {
 	var tgtPorts Ports
-	portRegistry, err := xmlParser.NewPortRegistry(dataFolder)
-	if err == nil {
-		portList := strings.Split(ports, ",")
-		for _, portArg := range portList {
-			if strings.Contains(portArg, "-") {
-				rawPorts := helper.StrRangeToArray(portArg)
-				for _, rawPort := range rawPorts {
-					if helper.ValidatePort(strconv.Itoa(rawPort)) {
-						var found bool
-						for _, p := range portRegistry.Records {
-							found = false
-							if p.Protocol == proto {
-								pNum, err := strconv.Atoi(p.Number)
-								if err == nil && pNum == rawPort {
-									tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, p.Service, p.Description))
-									found = true
-									break
-								} else if strings.Contains(p.Number, "-") {
-									pRange := helper.StrRangeToArray(p.Number)
-									for _, n := range pRange {
-										if n == rawPort {
-											tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, p.Service, p.Description))
-											found = true
-											break
-										}
-									}
-								}
-							}
-						}
-						if !found {
-							tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, "N/A",
-								"No description available"))
-						}
-					}
-				}
-			} else {
-				if helper.ValidatePort(portArg) {
-					rawPort, err := strconv.ParseUint(portArg, 10, 16)
-					if err == nil {
-						var found bool
-						for _, p := range portRegistry.Records {
-							found = false
-							if p.Protocol == proto {
-								pNum, err := strconv.Atoi(p.Number)
-								if err == nil && uint64(pNum) == rawPort {
-									tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, p.Service, p.Description))
-									found = true
-									break
-								} else if strings.Contains(p.Number, "-") {
-									pRange := helper.StrRangeToArray(p.Number)
-									for _, n := range pRange {
-										if uint64(n) == rawPort {
-											tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, p.Service, p.Description))
-											found = true
-											break
-										}
-									}
-								}
-							}
-						}
-						if !found {
-							tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, "N/A",
-								"No description available"))
-						}
-					}
-				}
-			}
+	portRegistry, err := loadPortRegistry(dataFolder)
+	for _, portNo := range parsePortNumbers(ports) {
+		if err != nil {
+			tgtPorts = append(tgtPorts, NewPort(portNo, proto, "N/A",
+				"Make sure you have provided the service-names-port-numbers.xml file"))
+		} else if record := portRegistry.Lookup(proto, portNo); record != nil {
+			tgtPorts = append(tgtPorts, NewPort(portNo, proto, record.Service, record.Description))
+		} else {
+			tgtPorts = append(tgtPorts, NewPort(portNo, proto, "N/A", "No description available"))
 		}
-		return tgtPorts
 	}
-	portList := strings.Split(ports, ",")
-	for _, portArg := range portList {
+	return tgtPorts
+}
+
+// loadPortRegistry returns the PortRegistry of dataFolder. The registry is loaded on the first call for dataFolder
+// and reused afterwards. Registries that failed to load aren't cached, so the next call tries again.
+func loadPortRegistry(dataFolder string) (*xmlParser.PortRegistry, error) {
+	portRegistries.Lock()
+	defer portRegistries.Unlock()
+	if r, ok := portRegistries.byFolder[dataFolder]; ok {
+		return r, nil
+	}
+	r, err := xmlParser.NewPortRegistry(dataFolder)
+	if err != nil {
+		return nil, err
+	}
+	portRegistries.byFolder[dataFolder] = r
+	return r, nil
+}
+
+// parsePortNumbers returns the valid port numbers of the comma separated list of single ports and port ranges ports.
+func parsePortNumbers(ports string) []uint16 {
+	var portNos []uint16
+	for _, portArg := range strings.Split(ports, ",") {
 		if strings.Contains(portArg, "-") {
-			rawPorts := helper.StrRangeToArray(portArg)
-			for _, rawPort := range rawPorts {
+			for _, rawPort := range helper.StrRangeToArray(portArg) {
 				if helper.ValidatePort(strconv.Itoa(rawPort)) {
-					tgtPorts = append(tgtPorts, NewPort(uint16(rawPort), proto, "N/A",
-						"Make sure you have provided the service-names-port-numbers.xml file"))
+					portNos = append(portNos, uint16(rawPort))
 				}
 			}
-		} else {
-			if helper.ValidatePort(portArg) {
-				p, _ := strconv.ParseUint(portArg, 10, 16)
-				tgtPorts = append(tgtPorts, NewPort(uint16(p), proto, "N/A",
-					"Make sure you have provided the service-names-port-numbers.xml file"))
-			}
+		} else if helper.ValidatePort(portArg) {
+			p, _ := strconv.ParseUint(portArg, 10, 16)
+			portNos = append(portNos, uint16(p))
 		}
 	}
-	return tgtPorts
+	return portNos
 }
 
 // String returns a string representation of the Port pointer.
@@ -169,6 +133,22 @@ func (p *Port) String() string {
 	return fmt.Sprintf("%5d/%s [%s]", p.PortNo, p.Protocol, p.Service)
 }
 
+// Unique returns the Ports without duplicates. Ports are duplicates if they have the same port number and protocol.
+// The order of the first occurrences is kept.
+func (ps Ports) Unique() Ports {
+	seen := make(map[string]bool, len(ps))
+	var unique Ports
+	for _, p := range ps {
+		key := fmt.Sprintf("%d/%s", p.PortNo, p.Protocol)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, p)
+	}
+	return unique
+}
+
 // String returns a string representation of Ports.
 func (ps Ports) String() string {
 	ret := ""